@@ -1,83 +1,372 @@
 package main
 
 import (
+   "bufio"
    "context"
+   "encoding/json"
+   "errors"
    "fmt"
+   "io"
    "os"
-   "os/exec"
    "path/filepath"
+   "slices"
    "strings"
+   "time"
 
+   "github.com/omnikron13/zelkata/config"
+   "github.com/omnikron13/zelkata/drafts"
+   "github.com/omnikron13/zelkata/lock"
    "github.com/omnikron13/zelkata/note"
    "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/search"
    "github.com/omnikron13/zelkata/tags"
+   "github.com/omnikron13/zelkata/templates"
+   "github.com/omnikron13/zelkata/txn"
 
    "github.com/charmbracelet/bubbles/textinput"
    tea "github.com/charmbracelet/bubbletea"
+   "github.com/charmbracelet/lipgloss"
    "github.com/urfave/cli/v3"
    "k8s.io/apimachinery/pkg/util/sets"
 )
 
 
+// addCmd adds a new note. Interactively the body is written in $EDITOR and the tags entered at a prompt, but if the
+// body is given with --body, or read from stdin with a "-" argument, nothing interactive is done at all so that notes
+// can be added from scripts. Either way the ID of the new note is printed, so that whatever added it knows what it is.
 func addCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() > 1 || (cmd.NArg() == 1 && cmd.Args().First() != "-") {
+      return errors.New(`add takes no arguments other than "-", to read the note body from stdin`)
+   }
+   if cmd.NArg() == 1 && cmd.IsSet("body") {
+      return errors.New(`the note body can't be both read from stdin and given with --body`)
+   }
+   interactive := cmd.NArg() == 0 && !cmd.IsSet("body")
+
+   // Create a new Note, including initialising the Meta struct (so this is when the UUID & timestamp are generated),
+   // either empty or from a template, which may need to ask for some details before the editor is opened
+   note := note.New("")
+   if name := cmd.String("template"); name != "" {
+      tmpl, err := templates.Load(name)
+      if err != nil {
+         return err
+      }
+      prompt, err := templatePrompt(cmd.StringSlice("set"), cmd.NArg() == 1)
+      if err != nil {
+         return err
+      }
+      if note, err = tmpl.Note(prompt); err != nil {
+         return err
+      }
+   } else if cmd.IsSet("set") {
+      return errors.New("--set only answers the prompts of a --template")
+   }
+   if cmd.IsSet("title") {
+      title := cmd.String("title")
+      note.Title = &title
+   }
+   note.Tags = sets.New(cmd.StringSlice("tag")...).Union(note.Tags)
+
+   switch {
+      case cmd.IsSet("body"):
+         note.Body = cmd.String("body")
+      case !interactive:
+         b, err := io.ReadAll(stdin)
+         if err != nil {
+            return err
+         }
+         note.Body = string(b)
+      default:
+         // Offer to pick up where an interrupted add left off, unless this is clearly meant to be a different note. The
+         // claim on the draft is held until it is discarded, so no other add offers it while it's being worked on here.
+         var claim *drafts.Lease
+         if !cmd.IsSet("template") && !cmd.IsSet("title") && !cmd.IsSet("tag") {
+            d, c, err := offerResume()
+            if err != nil {
+               return err
+            }
+            if d != nil {
+               note, claim = d.Note, c
+            }
+         }
+         if claim == nil {
+            c, err := drafts.Claim(note.ID)
+            if err != nil {
+               return err
+            }
+            claim = c
+         }
+         defer claim.Release()
+         if err := composeNote(&note); err != nil {
+            return err
+         }
+   }
+
+   if !interactive && strings.TrimSpace(note.Body) == "" {
+      return errors.New("not adding a note with an empty body")
+   }
+
+   if err := saveNewNote(&note); err != nil {
+      if interactive {
+         return fmt.Errorf("%w\nthe draft has been kept, and can be resumed with: zelkata drafts resume %s", err, note.ID)
+      }
+      return err
+   }
+   // Only now that the note is safely saved can its draft go
+   if interactive {
+      if err := drafts.Discard(note.ID); err != nil {
+         return err
+      }
+   }
+
+   if cmd.Bool("json") {
+      return printNoteJSON(&note)
+   }
+   fmt.Println(note.ID)
+   return nil
+}
+
+
+// composeNote opens the body of a new note in $EDITOR, reads back whatever was written, and then asks for its tags.
+// The note is kept as a draft throughout, so that it can be resumed if anything goes wrong before it is saved.
+func composeNote(n *note.Note) error {
    // TODO: default to a bubbletea(bubbles) TextArea, with a hotkey to launch a full editor?
-   // This sets up launching an external editor to write the note body, which is temporarily stored in a state file,
-   // which potentially also acts as a draft file if the user saves while editing but the add process is interrupted.
-   newNoteFile := filepath.Join(paths.State(), "new-note.md")
-   editCmd := exec.Command(os.Getenv("EDITOR"), newNoteFile)
-   editCmd.Stdin  = os.Stdin
-   editCmd.Stdout = os.Stdout
-   editCmd.Stderr = os.Stderr
-
-   // Start the editor and wait for it to finish
-   if err := editCmd.Start(); err != nil {
+   if err := drafts.Save(n); err != nil {
+      return err
+   }
+   if err := runEditor(drafts.BodyPath(n.ID)); err != nil {
       return err
    }
-   editCmd.Wait()
+   s, err := os.ReadFile(drafts.BodyPath(n.ID))
+   if err != nil {
+      return err
+   }
+   n.Body = string(s)
 
-   // Read the draft note file into a string and clear it so the next add has an empty file buffer
-   s, err := os.ReadFile(newNoteFile)
+   // Spin up bubbletea (crudely, for now) to get the tags for the note, starting with any already given
+   tm, err := tags.LoadAll()
+   if err != nil {
+      return err
+   }
+   ix, err := search.Load()
+   if err != nil {
+      return err
+   }
+   suggested := search.SuggestTags(ix, tm, n, maxTagSuggestions)
+   m, err := tea.NewProgram(initialAddCmdModel(tm, sets.List(n.Tags), suggested)).Run()
    if err != nil {
       return err
    }
-   os.Remove(newNoteFile)
+   n.Tags = sets.New(m.(addCmdModel).tags...)
+   if err := drafts.SaveMeta(&n.Meta); err != nil {
+      return err
+   }
+   if m.(addCmdModel).aborted {
+      return fmt.Errorf("adding the note was aborted; the draft has been kept, and can be resumed with: " +
+         "zelkata drafts resume %s", n.ID)
+   }
+   return nil
+}
 
-   // Create a new Note, including initialising the Meta struct (so this is when the UUID & timestamp are generated)
-   note := note.New(string(s))
 
-   // Spin up bubbletea (crudely, for now) to get the tags for the note
-   var m tea.Model
-   if m, err = tea.NewProgram(initialAddCmdModel()).Run(); err != nil {
+// saveNewNote saves a new note to the configured notes dir with the configured filename, and adds it to its tags; all
+// in one go, so the note and its tags can't end up disagreeing.
+func saveNewNote(n *note.Note) error {
+   if err := checkAssignable(n.Tags); err != nil {
+      return err
+   }
+   if err := checkUntagged(n); err != nil {
       return err
    }
-   acm := m.(addCmdModel)
+   return withLock(lock.Exclusive, func() error {
+      tx := txn.New()
+      if err := n.SaveTx(tx, filepath.Join(paths.Notes(), n.GenFileName())); err != nil {
+         return err
+      }
+      if err := tags.RetagTx(tx, n.ID, nil, n.Tags); err != nil {
+         return err
+      }
+      return tx.Commit()
+   })
+}
 
-   // Actually add the tags to the Note
-   note.Tags = sets.New(acm.tags...)
 
-   // Save the note to the configured notes dir with the configured filename
-   if err := note.Save(); err != nil {
+// checkAssignable returns an error if any of the named tags can't be given to a note, i.e. is virtual; checked before a
+// note is saved, so it isn't left saved with tags that can't be updated to match.
+func checkAssignable(names sets.Set[string]) error {
+   tm, err := tags.LoadAll()
+   if err != nil {
       return err
    }
+   return tm.CheckAssignable(names)
+}
 
-   // Update the specified tags with the new note ID
-   for s := range note.Tags {
-      if t, err := tags.LoadOrCreate(s); err != nil { return err } else
-         { t.AddNote(&note) }
+
+// checkUntagged warns that a note has no tags, or refuses it outright, depending on the config.
+func checkUntagged(n *note.Note) error {
+   if len(n.Tags) > 0 {
+      return nil
+   }
+   switch policy := config.GetOrPanic[string]("tags.untagged"); policy {
+      case "warn":
+         fmt.Fprintf(os.Stderr, "warning: note %s has no tags, so will only be found under %q\n", n.ID, tags.Untagged)
+         return nil
+      case "refuse":
+         return errors.New("note has no tags, and tags.untagged is set to refuse untagged notes")
+      default:
+         return fmt.Errorf("unsupported tags.untagged setting: %s", policy)
    }
+}
 
-   // Return nil if everything went well
-   return nil
+
+// offerResume asks whether to resume the most recent draft, if there are any, returning it claimed if so. Drafts which
+// another process is working on (e.g. an add running in another terminal) aren't offered, including any claimed since
+// they were listed.
+func offerResume() (*drafts.Draft, *drafts.Lease, error) {
+   list, err := drafts.List()
+   if err != nil {
+      return nil, nil, err
+   }
+   list = slices.DeleteFunc(list, func(d drafts.Draft) bool { return d.InUse })
+   for i := range list {
+      d, claim, err := drafts.LoadClaimed(list[i].ID)
+      if errors.Is(err, drafts.ErrInUse) || errors.Is(err, drafts.ErrNotFound) {
+         continue
+      }
+      if err != nil {
+         return nil, nil, err
+      }
+      fmt.Fprintf(os.Stderr, "%d unfinished draft(s) found; the latest is %s, last written %s\n",
+         len(list) - i, draftTitle(&d), d.Modified.Format(time.DateTime))
+      answer, err := promptLine("Resume it? [y/N]")
+      if err != nil || (!strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes")) {
+         return nil, nil, errors.Join(err, claim.Release())
+      }
+      return &d, claim, nil
+   }
+   return nil, nil, nil
 }
 
 
-// BubbleTea code for adding the tags to a Note
+// noteJSON is the description of a note printed by add --json.
+type noteJSON struct {
+   ID string `json:"id"`
+   Path string `json:"path"`
+   Created time.Time `json:"created"`
+   Title string `json:"title,omitempty"`
+   Tags []string `json:"tags"`
+}
+
+
+// printNoteJSON prints a JSON description of a saved note to stdout.
+func printNoteJSON(n *note.Note) error {
+   return json.NewEncoder(os.Stdout).Encode(noteJSON{
+      ID: n.ID,
+      Path: filepath.Join(paths.Notes(), n.GenFileName()),
+      Created: n.Created,
+      Title: n.DisplayTitle(),
+      Tags: sets.List(n.Tags),
+   })
+}
+
+
+// templatePrompt returns the templates.PromptFunc for filling in a template; answers given as LABEL=VALUE with --set
+// are used first, and anything else is asked for on the terminal. If the body is being read from stdin, or stdin isn't
+// a terminal at all, there is no asking, as the answers would be taken from whatever is being piped in.
+func templatePrompt(set []string, bodyOnStdin bool) (templates.PromptFunc, error) {
+   answers := map[string]string{}
+   for _, s := range set {
+      label, value, ok := strings.Cut(s, "=")
+      if !ok {
+         return nil, fmt.Errorf("--set expects LABEL=VALUE, not %q", s)
+      }
+      answers[label] = value
+   }
+   canPrompt := !bodyOnStdin && stdinIsTerminal()
+   return func(label string) (string, error) {
+      if a, ok := answers[label]; ok {
+         return a, nil
+      }
+      if !canPrompt {
+         return "", fmt.Errorf("the template asks for %q, which can't be prompted for without a terminal; " +
+            "give it with --set %q", label, label + "=...")
+      }
+      return promptLine(label)
+   }, nil
+}
+
+
+// stdinIsTerminal reports whether stdin is a terminal, rather than e.g. a pipe or a file.
+func stdinIsTerminal() bool {
+   info, err := os.Stdin.Stat()
+   return err == nil && info.Mode() & os.ModeCharDevice != 0
+}
+
+
+// stdin is shared by everything reading lines from standard input, so that nothing read ahead into one buffer is lost
+// to the next reader.
+var stdin = bufio.NewReader(os.Stdin)
+
+
+// promptLine asks the user for a single line of input on the terminal, for filling in template placeholders.
+func promptLine(label string) (string, error) {
+   // The prompt goes to stderr, keeping stdout for output which might be captured
+   fmt.Fprintf(os.Stderr, "%s: ", label)
+   line, err := stdin.ReadString('\n')
+   if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+      return "", err
+   }
+   return strings.TrimSpace(line), nil
+}
+
+
+// BubbleTea code for adding the tags to a Note. Existing tags are fuzzy matched as the user types, so that they can be
+// completed rather than retyped, and so that a typo doesn't silently create a new tag; a tag which doesn't exist yet
+// has to be confirmed by pressing Enter a second time. Tags suggested from the content of the note can be accepted with
+// alt and their number.
 type addCmdModel struct {
    tags []string
    input textinput.Model
+   tagMap tags.TagMap
+   suggested []search.TagSuggestion
+   suggestions []tags.Suggestion
+   selected int
+   confirmNew string
+   rejected string
+   aborted bool
    err error
 }
 
+// maxSuggestions is the most tags suggested at once in the add tags prompt.
+const maxSuggestions = 5
+
+// maxTagSuggestions is the most tags suggested from the content of the note in the add tags prompt.
+const maxTagSuggestions = 5
+
+// selectedStyle picks out the selected suggestion in the add tags prompt.
+var selectedStyle = lipgloss.NewStyle().Bold(true)
+
+// warningStyle is used for the warning shown before a new tag is created.
+var warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+// matchStyle picks out the characters of a suggested tag which matched what has been typed.
+var matchStyle = lipgloss.NewStyle().Underline(true)
+
+
+// highlightMatch renders s in the given style, with the characters at the matched byte indexes also in matchStyle.
+func highlightMatch(s string, matched []int, style lipgloss.Style) string {
+   var sb strings.Builder
+   for i, r := range s {
+      if slices.Contains(matched, i) {
+         sb.WriteString(style.Inherit(matchStyle).Render(string(r)))
+      } else {
+         sb.WriteString(style.Render(string(r)))
+      }
+   }
+   return sb.String()
+}
+
+
 func (m addCmdModel) Init() tea.Cmd {
    // Just return `nil`, which means "no I/O right now, please."
    //return nil
@@ -91,15 +380,54 @@ func (m addCmdModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
       case tea.KeyMsg:
          switch msg.Type {
             case tea.KeyCtrlC, tea.KeyEsc:
+               m.aborted = true
                return m, tea.Quit
 
+            case tea.KeyTab:
+               if len(m.suggestions) > 0 {
+                  m.input.SetValue(m.suggestions[m.selected].Tag.Name)
+                  m.input.CursorEnd()
+                  m.confirmNew = ""
+               }
+               return m, nil
+
+            case tea.KeyUp:
+               if m.selected > 0 {
+                  m.selected--
+               }
+               return m, nil
+
+            case tea.KeyDown:
+               if m.selected < len(m.suggestions) - 1 {
+                  m.selected++
+               }
+               return m, nil
+
+            case tea.KeyRunes:
+               if r := msg.Runes[0]; msg.Alt && len(msg.Runes) == 1 && r >= '1' && r <= '9' {
+                  if i := int(r - '1'); i < len(m.suggested) {
+                     m = m.addTag(m.suggested[i].Tag.Name)
+                     m.suggested = slices.Delete(m.suggested, i, i+1)
+                  }
+                  return m, nil
+               }
+
             case tea.KeyEnter:
                s := strings.TrimSpace(m.input.Value())
                if s == "" {
                   return m, tea.Quit
                }
-               m.tags = append(m.tags, s)
-               m.input.SetValue("")
+               m.rejected = ""
+               if t := m.findTag(s); t != nil && t.Virtual {
+                  m.rejected = t.Name
+                  return m, nil
+               } else if t != nil {
+                  s = t.Name
+               } else if m.confirmNew != s {
+                  m.confirmNew = s
+                  return m, nil
+               }
+               return m.addTag(s), nil
          }
 
       // We handle errors just like any other message
@@ -108,19 +436,89 @@ func (m addCmdModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
          return m, nil
    }
 
+   before := m.input.Value()
    m.input, cmd = m.input.Update(msg)
+   if v := m.input.Value(); v != before {
+      m.suggestions = m.tagMap.Suggest(strings.TrimSpace(v), maxSuggestions)
+      m.selected = 0
+      m.confirmNew = ""
+      m.rejected = ""
+   }
    return m, cmd
 }
 
+// addTag adds a tag to the note, if it isn't already there, and clears the input ready for the next.
+func (m addCmdModel) addTag(name string) addCmdModel {
+   if !slices.Contains(m.tags, name) {
+      m.tags = append(m.tags, name)
+   }
+   m.input.SetValue("")
+   m.suggestions = nil
+   m.confirmNew = ""
+   return m
+}
+
+// findTag returns the existing tag with the given name or alias, ignoring case, or nil if there isn't one.
+func (m addCmdModel) findTag(name string) *tags.Tag {
+   if t := m.tagMap.Get(name); t != nil {
+      return t
+   }
+   for _, sg := range m.suggestions {
+      if strings.EqualFold(sg.Match, name) {
+         return sg.Tag
+      }
+   }
+   return nil
+}
+
 func (m addCmdModel) View() string {
    var sb strings.Builder
    for _, t := range m.tags {
-      sb.WriteString("" + t + " ")
+      sb.WriteString("\uF02B" + t + " ")
+   }
+   sb.WriteString("\n" + m.input.View() + "\n")
+   for i, sg := range m.suggestions {
+      style, line := lipgloss.NewStyle(), "  "
+      if i == m.selected {
+         style, line = selectedStyle, "> "
+      }
+      if sg.Tag.Icon != "" {
+         line += sg.Tag.Icon + " "
+      }
+      // The typed characters are picked out in whichever of the name or alias they matched
+      if sg.Match == sg.Tag.Name {
+         line = style.Render(line) + highlightMatch(sg.Tag.Name, sg.MatchedIndexes, style)
+         line += style.Render(fmt.Sprintf(" (%d notes)", sg.Tag.Notes.Len()))
+      } else {
+         line = style.Render(fmt.Sprintf("%s%s (%d notes) as ", line, sg.Tag.Name, sg.Tag.Notes.Len()))
+         line += highlightMatch(sg.Match, sg.MatchedIndexes, style)
+      }
+      sb.WriteString(line + "\n")
+   }
+   if m.confirmNew != "" {
+      warning := fmt.Sprintf("%q is a new tag; press Enter again to create it", m.confirmNew)
+      if len(m.suggestions) > 0 {
+         warning += fmt.Sprintf(", or Tab to use %s instead", m.suggestions[m.selected].Tag.Name)
+      }
+      sb.WriteString(warningStyle.Render(warning))
+      sb.WriteString("\n")
    }
-   return fmt.Sprintf("%s\n%s\n(Enter blank to finish adding tags)\n", sb.String(), m.input.View())
+   if m.rejected != "" {
+      sb.WriteString(warningStyle.Render(fmt.Sprintf("%s is a virtual tag, so can't be added to notes", m.rejected)))
+      sb.WriteString("\n")
+   }
+   if len(m.suggested) > 0 {
+      sb.WriteString("Suggested:")
+      for i, sg := range m.suggested {
+         fmt.Fprintf(&sb, "  [alt+%d] %s (%.2f)", i + 1, sg.Tag.Name, sg.Score)
+      }
+      sb.WriteString("\n")
+   }
+   sb.WriteString("(Tab to complete, Enter blank to finish adding tags)\n")
+   return sb.String()
 }
 
-func initialAddCmdModel() addCmdModel {
+func initialAddCmdModel(tm tags.TagMap, initial []string, suggested []search.TagSuggestion) addCmdModel {
    ti := textinput.New()
    ti.Prompt = "Add tag: "
    ti.Focus()
@@ -128,9 +526,10 @@ func initialAddCmdModel() addCmdModel {
    ti.Width = 20
 
    return addCmdModel{
-      tags: []string{},
+      tags: initial,
       input: ti,
+      tagMap: tm,
+      suggested: suggested,
       err: nil,
    }
 }
-
@@ -0,0 +1,89 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "fmt"
+   "os"
+   "strings"
+   "time"
+
+   "github.com/omnikron13/zelkata/drafts"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// draftsLsCmd lists the drafts of notes which were never finished being added, most recent first.
+func draftsLsCmd(ctx context.Context, cmd *cli.Command) error {
+   list, err := drafts.List()
+   if err != nil {
+      return err
+   }
+   for _, d := range list {
+      title := draftTitle(&d)
+      if d.InUse {
+         title += " (in use)"
+      }
+      fmt.Printf("%s\t%s\t%s\n", d.ID, d.Modified.Format(time.DateTime), title)
+   }
+   return nil
+}
+
+
+// draftsResumeCmd carries on adding a note from a draft, exactly as add would have.
+func draftsResumeCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 1 {
+      return errors.New("resume requires exactly one draft ID")
+   }
+   d, claim, err := drafts.LoadClaimed(cmd.Args().First())
+   if err != nil {
+      return err
+   }
+   defer claim.Release()
+   if err := composeNote(&d.Note); err != nil {
+      return err
+   }
+   if err := saveNewNote(&d.Note); err != nil {
+      return err
+   }
+   if err := drafts.Discard(d.ID); err != nil {
+      return err
+   }
+   fmt.Println(d.ID)
+   return nil
+}
+
+
+// draftsDiscardCmd throws away a draft.
+func draftsDiscardCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 1 {
+      return errors.New("discard requires exactly one draft ID")
+   }
+   d, claim, err := drafts.LoadClaimed(cmd.Args().First())
+   if err != nil {
+      return err
+   }
+   defer claim.Release()
+   if err := drafts.Discard(d.ID); err != nil {
+      return err
+   }
+   fmt.Fprintf(os.Stderr, "discarded draft %s\n", d.ID)
+   return nil
+}
+
+
+// draftTitle returns something to identify a draft by; its title if it has one yet, or else the start of its body.
+func draftTitle(d *drafts.Draft) string {
+   if t := d.DisplayTitle(); t != "" {
+      return t
+   }
+   line, _, _ := strings.Cut(strings.TrimSpace(d.Body), "\n")
+   if r := []rune(line); len(r) > 50 {
+      line = string(r[:50]) + "…"
+   }
+   if line == "" {
+      return "(empty)"
+   }
+   return line
+}
@@ -0,0 +1,309 @@
+// Package drafts keeps notes which are in the middle of being added, so that nothing written is lost if adding one is
+// interrupted, and so that several can be added at once without getting in each other's way. Each draft is a pair of
+// files in the drafts directory named for the ID of the note being added; the body, which is what is opened in the
+// editor, and a YAML sidecar holding the note's Meta as it stands so far. A process working on a draft also holds a
+// lock file beside it, so that the draft isn't offered to be resumed anywhere else in the meantime.
+package drafts
+
+import (
+   "bytes"
+   "errors"
+   "fmt"
+   "os"
+   "path/filepath"
+   "slices"
+   "strings"
+   "time"
+
+   "github.com/omnikron13/zelkata/lock"
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/txn"
+
+   "gopkg.in/yaml.v3"
+)
+
+const (
+   // bodyExt is the extension of draft bodies.
+   bodyExt = ".md"
+
+   // metaExt is the extension of the Meta sidecars of drafts.
+   metaExt = ".meta.yaml"
+
+   // lockExt is the extension of the lock files of drafts.
+   lockExt = ".lock"
+)
+
+
+// ErrInUse is returned when claiming a draft which another process is already working on.
+var ErrInUse = errors.New("the draft is being worked on by another zelkata process")
+
+// ErrNotFound is returned when there is no draft with the given ID.
+var ErrNotFound = errors.New("no draft found")
+
+
+// Draft is a note which hasn't been added yet.
+type Draft struct {
+   note.Note
+
+   // Modified is when the draft body was last written to.
+   Modified time.Time
+
+   // InUse is whether another process is working on the draft, e.g. an add still running in another terminal.
+   InUse bool
+}
+
+
+// BodyPath returns the path of the body file of the draft of the note with the given ID.
+func BodyPath(id string) string {
+   return filepath.Join(paths.Drafts(), id + bodyExt)
+}
+
+
+// metaPath returns the path of the Meta sidecar of the draft of the note with the given ID.
+func metaPath(id string) string {
+   return filepath.Join(paths.Drafts(), id + metaExt)
+}
+
+
+// lockPath returns the path of the lock file of the draft of the note with the given ID.
+func lockPath(id string) string {
+   return filepath.Join(paths.Drafts(), id + lockExt)
+}
+
+
+// Lease is a claim by this process on a draft, held from Claim until Release.
+type Lease struct {
+   id string
+   l *lock.Lock
+}
+
+
+// Claim marks the draft of the note with the given full ID as being worked on by this process until the claim is
+// released, or returns ErrInUse if another process already has it. The draft needn't exist yet; a new note should be
+// claimed before its draft is first saved, so that it is never seen unclaimed. A draft which already exists should be
+// read again once claimed, as LoadClaimed does, as it may have been changed or discarded since it was last read.
+func Claim(id string) (*Lease, error) {
+   l, err := lock.TryAcquire(lockPath(id), lock.Exclusive)
+   if errors.Is(err, lock.ErrLocked) {
+      return nil, fmt.Errorf("%w: %s", ErrInUse, id)
+   }
+   if err != nil {
+      return nil, err
+   }
+   return &Lease{id: id, l: l}, nil
+}
+
+
+// Release releases the claim, and then removes the lock file if the draft has been discarded. It is only removed once
+// released, so that nothing else can ever be holding a lock on a lock file which is no longer there.
+func (c *Lease) Release() error {
+   if err := c.l.Release(); err != nil {
+      return err
+   }
+   if _, err := os.Stat(metaPath(c.id)); !errors.Is(err, os.ErrNotExist) {
+      return nil
+   }
+   if err := os.Remove(lockPath(c.id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+      return err
+   }
+   return nil
+}
+
+
+// LoadClaimed claims the draft of the note with the given ID, which may be abbreviated to any unambiguous prefix, and
+// then reads it; so that what is read can't be from before another process changed or discarded it.
+func LoadClaimed(id string) (Draft, *Lease, error) {
+   d, err := Load(id)
+   if err != nil {
+      return d, nil, err
+   }
+   c, err := Claim(d.ID)
+   if err != nil {
+      return d, nil, err
+   }
+   if d, err = read(d.ID); err != nil {
+      c.Release()
+      if errors.Is(err, os.ErrNotExist) {
+         err = fmt.Errorf("%w with ID %q", ErrNotFound, id)
+      }
+      return d, nil, err
+   }
+   // It is only in use by this process
+   d.InUse = false
+   return d, c, nil
+}
+
+
+// inUse reports whether another process has claimed the draft with the given full ID. This only takes a shared lock to
+// look, so that several processes looking at once don't make the draft appear in use to each other.
+func inUse(id string) (bool, error) {
+   l, err := lock.TryAcquire(lockPath(id), lock.Shared)
+   if errors.Is(err, lock.ErrLocked) {
+      return true, nil
+   }
+   if err != nil {
+      return false, err
+   }
+   return false, l.Release()
+}
+
+
+// Save writes the note as a draft, replacing any previous draft of it.
+func Save(n *note.Note) error {
+   yml, err := yaml.Marshal(&n.Meta)
+   if err != nil {
+      return err
+   }
+   if err := txn.WriteFile(metaPath(n.ID), yml, 0600); err != nil {
+      return err
+   }
+   return txn.WriteFile(BodyPath(n.ID), []byte(n.Body), 0600)
+}
+
+
+// SaveMeta writes just the Meta of the note to its draft, leaving the body as it is on disk; for when the body is
+// being written in an editor and the rest of the note is being filled in around it.
+func SaveMeta(m *note.Meta) error {
+   yml, err := yaml.Marshal(m)
+   if err != nil {
+      return err
+   }
+   return txn.WriteFile(metaPath(m.ID), yml, 0600)
+}
+
+
+// Load reads the draft of the note with the given ID, which may be abbreviated to any unambiguous prefix.
+func Load(id string) (d Draft, err error) {
+   all, err := ids()
+   if err != nil {
+      return
+   }
+   var matches []string
+   for _, full := range all {
+      if full == id {
+         matches = []string{full}
+         break
+      }
+      if strings.HasPrefix(full, id) {
+         matches = append(matches, full)
+      }
+   }
+   switch {
+      case id == "" || len(matches) == 0:
+         return d, fmt.Errorf("%w with ID %q", ErrNotFound, id)
+      case len(matches) > 1:
+         return d, &note.AmbiguousIDError{Prefix: id, Candidates: matches}
+   }
+   return read(matches[0])
+}
+
+
+// read reads the draft with the given full ID.
+func read(id string) (d Draft, err error) {
+   yml, err := os.ReadFile(metaPath(id))
+   if err != nil {
+      return
+   }
+   if err = yaml.Unmarshal(yml, &d.Meta); err != nil {
+      return d, fmt.Errorf("draft %s: %w", id, err)
+   }
+   if d.InUse, err = inUse(id); err != nil {
+      return
+   }
+   body, err := os.ReadFile(BodyPath(id))
+   if errors.Is(err, os.ErrNotExist) {
+      // The editor may never have written anything
+      return d, nil
+   }
+   if err != nil {
+      return
+   }
+   d.Body = string(body)
+   if info, err := os.Stat(BodyPath(id)); err == nil {
+      d.Modified = info.ModTime()
+   }
+   return d, nil
+}
+
+
+// List returns all of the drafts, most recently modified first.
+func List() ([]Draft, error) {
+   all, err := ids()
+   if err != nil {
+      return nil, err
+   }
+   drafts := make([]Draft, 0, len(all))
+   for _, id := range all {
+      d, err := read(id)
+      if err != nil {
+         return nil, err
+      }
+      drafts = append(drafts, d)
+   }
+   slices.SortStableFunc(drafts, func(a, b Draft) int { return b.Modified.Compare(a.Modified) })
+   return drafts, nil
+}
+
+
+// ids returns the IDs of all the drafts, sorted, after first migrating any draft left behind by an older version.
+func ids() (ids []string, err error) {
+   if err = migrateLegacy(); err != nil {
+      return
+   }
+   entries, err := os.ReadDir(paths.Drafts())
+   if err != nil {
+      return
+   }
+   for _, e := range entries {
+      if id, ok := strings.CutSuffix(e.Name(), metaExt); ok && e.Type().IsRegular() {
+         ids = append(ids, id)
+      }
+   }
+   slices.Sort(ids)
+   return
+}
+
+
+// Discard removes the draft of the note with the given full ID. Its lock file is left for the claim on it (if any) to
+// remove once released.
+func Discard(id string) error {
+   if err := os.Remove(BodyPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+      return err
+   }
+   return os.Remove(metaPath(id))
+}
+
+
+// legacyPath returns where versions of Zelkata from before drafts kept the body of the note being added; it was only
+// removed once the body had been read back from the editor, so if it's there adding that note was interrupted.
+func legacyPath() string {
+   return filepath.Join(paths.State(), "new-note.md")
+}
+
+
+// migrateLegacy turns a body left behind by an older version into a draft like any other, so that it can be resumed.
+func migrateLegacy() error {
+   info, err := os.Stat(legacyPath())
+   if errors.Is(err, os.ErrNotExist) {
+      return nil
+   }
+   if err != nil {
+      return err
+   }
+   body, err := os.ReadFile(legacyPath())
+   if err != nil {
+      return err
+   }
+   if len(bytes.TrimSpace(body)) > 0 {
+      n := note.New(string(body))
+      if err := Save(&n); err != nil {
+         return err
+      }
+      // Keep when it was written, as that's what drafts are ordered by
+      if err := os.Chtimes(BodyPath(n.ID), info.ModTime(), info.ModTime()); err != nil {
+         return err
+      }
+   }
+   return os.Remove(legacyPath())
+}
@@ -0,0 +1,107 @@
+package drafts
+
+import (
+   "errors"
+   "os"
+   "slices"
+   "testing"
+   "time"
+
+   "github.com/omnikron13/zelkata/internal/testenv"
+   "github.com/omnikron13/zelkata/note"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_Drafts(t *testing.T) {
+   testenv.Setup(t)
+   a := note.New("First draft.\n")
+   a.Tags = sets.New("Foo")
+   b := note.New("")
+   assert.Nil(t, Save(&a))
+   assert.Nil(t, Save(&b))
+
+   // Tags entered after the body was written are kept without touching the body
+   b.Tags = sets.New("Bar")
+   assert.Nil(t, os.WriteFile(BodyPath(b.ID), []byte("Second draft.\n"), 0600))
+   assert.Nil(t, SaveMeta(&b.Meta))
+
+   list, err := List()
+   assert.Nil(t, err)
+   assert.Len(t, list, 2)
+
+   d, err := Load(b.ID[:8])
+   assert.Nil(t, err)
+   assert.Equal(t, b.ID, d.ID)
+   assert.Equal(t, sets.New("Bar"), d.Tags)
+   assert.Equal(t, "Second draft.\n", d.Body)
+   assert.True(t, d.Created.Equal(b.Created.Truncate(1e9)))
+
+   assert.Nil(t, Discard(a.ID))
+   _, err = Load(a.ID)
+   assert.NotNil(t, err)
+   list, err = List()
+   assert.Nil(t, err)
+   if assert.Len(t, list, 1) {
+      assert.Equal(t, b.ID, list[0].ID)
+   }
+}
+
+
+func Test_Claim(t *testing.T) {
+   testenv.Setup(t)
+   n := note.New("Busy.\n")
+   claim, err := Claim(n.ID)
+   if !assert.Nil(t, err) {
+      return
+   }
+   assert.Nil(t, Save(&n))
+
+   // flock locks belong to the open file, so a second claim within one process contends just as another process would
+   _, err = Claim(n.ID)
+   assert.True(t, errors.Is(err, ErrInUse))
+   d, err := Load(n.ID)
+   assert.Nil(t, err)
+   assert.True(t, d.InUse)
+
+   assert.Nil(t, claim.Release())
+   d, err = Load(n.ID)
+   assert.Nil(t, err)
+   assert.False(t, d.InUse)
+
+   // The lock file outlives a discarded draft only until the claim on it is released
+   d, claim, err = LoadClaimed(n.ID[:8])
+   if !assert.Nil(t, err) {
+      return
+   }
+   assert.Equal(t, "Busy.\n", d.Body)
+   assert.False(t, d.InUse)
+   assert.Nil(t, Discard(n.ID))
+   _, err = os.Stat(lockPath(n.ID))
+   assert.Nil(t, err)
+   assert.Nil(t, claim.Release())
+   _, err = os.Stat(lockPath(n.ID))
+   assert.True(t, errors.Is(err, os.ErrNotExist))
+   _, _, err = LoadClaimed(n.ID)
+   assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+
+func Test_migrateLegacy(t *testing.T) {
+   testenv.Setup(t)
+   written := time.Now().Add(-time.Hour).Truncate(time.Second)
+   assert.Nil(t, os.WriteFile(legacyPath(), []byte("Left behind.\n"), 0600))
+   assert.Nil(t, os.Chtimes(legacyPath(), written, written))
+
+   list, err := List()
+   assert.Nil(t, err)
+   _, err = os.Stat(legacyPath())
+   assert.True(t, errors.Is(err, os.ErrNotExist))
+   i := slices.IndexFunc(list, func(d Draft) bool { return d.Body == "Left behind.\n" })
+   if assert.NotEqual(t, -1, i, "legacy draft should have been migrated") {
+      assert.True(t, list[i].Modified.Equal(written))
+      assert.Nil(t, Discard(list[i].ID))
+   }
+}
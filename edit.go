@@ -0,0 +1,85 @@
+package main
+
+import (
+   "bytes"
+   "context"
+   "errors"
+   "fmt"
+   "os"
+   "path/filepath"
+
+   "github.com/omnikron13/zelkata/lock"
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/tags"
+   "github.com/omnikron13/zelkata/txn"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// editCmd re-opens an existing note in $EDITOR, front matter and all. The note is edited via a copy in the state dir,
+// so if the result doesn't parse or validate the original is left untouched and the user's changes aren't lost either.
+func editCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 1 {
+      return errors.New("edit requires exactly one note ID")
+   }
+   path, err := note.FindByID(cmd.Args().First())
+   if err != nil {
+      return err
+   }
+   old, err := note.ReadFile(path)
+   if err != nil {
+      return err
+   }
+
+   b, err := os.ReadFile(path)
+   if err != nil {
+      return err
+   }
+   editFile := filepath.Join(paths.State(), "edit-" + old.ID + ".md")
+   if err := txn.WriteFile(editFile, b, 0600); err != nil {
+      return err
+   }
+   if err := runEditor(editFile); err != nil {
+      return err
+   }
+
+   n, err := note.ReadFile(editFile)
+   if err == nil {
+      err = n.Validate()
+   }
+   if err == nil && n.ID != old.ID {
+      err = fmt.Errorf("note ID cannot be changed (was %s, now %s)", old.ID, n.ID)
+   }
+   if err == nil {
+      err = checkAssignable(n.Tags.Difference(old.Tags))
+   }
+   if err == nil {
+      err = checkUntagged(&n)
+   }
+   if err != nil {
+      return fmt.Errorf("edited note is invalid, changes have been kept in %s: %w", editFile, err)
+   }
+
+   err = withLock(lock.Exclusive, func() error {
+      // Something else (e.g. another edit) may have changed the note while it was open in the editor
+      if current, err := os.ReadFile(path); err != nil {
+         return err
+      } else if !bytes.Equal(current, b) {
+         return fmt.Errorf("note was changed by something else while being edited, changes have been kept in %s", editFile)
+      }
+      tx := txn.New()
+      if err := n.SaveTx(tx, path); err != nil {
+         return err
+      }
+      if err := tags.RetagTx(tx, n.ID, old.Tags, n.Tags); err != nil {
+         return err
+      }
+      return tx.Commit()
+   })
+   if err != nil {
+      return err
+   }
+   return os.Remove(editFile)
+}
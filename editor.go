@@ -0,0 +1,16 @@
+package main
+
+import (
+   "os"
+   "os/exec"
+)
+
+
+// runEditor opens the given file in the user's $EDITOR, attached to the terminal, and waits for it to exit.
+func runEditor(path string) error {
+   editCmd := exec.Command(os.Getenv("EDITOR"), path)
+   editCmd.Stdin  = os.Stdin
+   editCmd.Stdout = os.Stdout
+   editCmd.Stderr = os.Stderr
+   return editCmd.Run()
+}
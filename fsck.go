@@ -0,0 +1,49 @@
+package main
+
+import (
+   "context"
+   "fmt"
+
+   "github.com/omnikron13/zelkata/fsck"
+   "github.com/omnikron13/zelkata/lock"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// fsckCmd checks the consistency of the notes and tags, printing a tab separated line for each problem found; its
+// kind, the file it was found in, and a description. With --fix, any problems which can be repaired are.
+func fsckCmd(ctx context.Context, cmd *cli.Command) error {
+   mode := lock.Shared
+   if cmd.Bool("fix") {
+      mode = lock.Exclusive
+   }
+   return withLock(mode, func() error { return fsckProblems(cmd.Bool("fix")) })
+}
+
+
+// fsckProblems checks the catalogue and prints the problems found, fixing them first where possible if fix is set.
+func fsckProblems(fix bool) error {
+   problems, err := fsck.Check()
+   if err != nil {
+      return err
+   }
+   remaining := 0
+   for _, p := range problems {
+      if fix && p.Fixable() {
+         if err := p.Fix(); err != nil {
+            fmt.Printf("%s\t(fix failed: %s)\n", p.String(), err)
+            remaining++
+         } else {
+            fmt.Printf("%s\t(fixed)\n", p.String())
+         }
+         continue
+      }
+      fmt.Println(p.String())
+      remaining++
+   }
+   if remaining > 0 {
+      return fmt.Errorf("%d problem(s) found", remaining)
+   }
+   return nil
+}
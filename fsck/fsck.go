@@ -0,0 +1,252 @@
+// Package fsck checks the consistency of a Zelkata catalogue; that every note can be read, that the tag files and the
+// notes agree about which notes have which tags, and that files are named as they should be. Problems are reported
+// rather than fixed by default, but many of them carry a fix which can be applied on request.
+package fsck
+
+import (
+   "cmp"
+   "errors"
+   "fmt"
+   "os"
+   "path/filepath"
+   "slices"
+   "strings"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/tags"
+
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+// Kind identifies the kind of a Problem.
+type Kind string
+
+const (
+   // ParseError is a note file which can't be read.
+   ParseError Kind = "parse-error"
+
+   // DuplicateID is a note with the same ID as another note.
+   DuplicateID Kind = "duplicate-id"
+
+   // FileName is a note file not named as GenFileName would name it.
+   FileName Kind = "filename"
+
+   // MissingNote is a tag file referencing a note ID which doesn't exist.
+   MissingNote Kind = "missing-note"
+
+   // MissingTag is a note with a tag which has no tag file.
+   MissingTag Kind = "missing-tag"
+
+   // UnindexedNote is a note with a tag whose tag file doesn't list the note.
+   UnindexedNote Kind = "unindexed-note"
+
+   // VirtualTag is a note with a virtual tag, which can't be assigned directly.
+   VirtualTag Kind = "virtual-tag"
+
+   // BrokenLink is a note linking to a note ID which doesn't exist.
+   BrokenLink Kind = "broken-link"
+)
+
+
+// Problem is a single inconsistency found by Check.
+type Problem struct {
+   // Kind is the kind of the problem.
+   Kind Kind
+
+   // Path is the file in which the problem was found.
+   Path string
+
+   // Message describes the problem in more detail.
+   Message string
+
+   // fix repairs the problem, or is nil if it can't be repaired automatically.
+   fix func() error
+}
+
+
+func (p *Problem) String() string {
+   return fmt.Sprintf("%s\t%s\t%s", p.Kind, p.Path, p.Message)
+}
+
+
+// Fixable reports whether the problem can be repaired automatically.
+func (p *Problem) Fixable() bool {
+   return p.fix != nil
+}
+
+
+// Fix repairs the problem, if it can be.
+func (p *Problem) Fix() error {
+   if p.fix == nil {
+      return errors.New("problem cannot be fixed automatically")
+   }
+   return p.fix()
+}
+
+
+// Check reads every note and tag and returns all of the problems found. An error is only returned if the check itself
+// couldn't be carried out, e.g. the tag files couldn't be loaded at all.
+func Check() (problems []Problem, err error) {
+   tm, err := tags.LoadAll()
+   if err != nil {
+      return nil, err
+   }
+   files, err := note.Files()
+   if err != nil {
+      return nil, err
+   }
+
+   notes := map[string]string{}
+   var parsed []note.Note
+   for _, f := range files {
+      n, err := note.ReadFile(f)
+      if err != nil {
+         msg := err.Error()
+         // The Problem already has the path, so only the line is wanted from a ParseError
+         var pe *note.ParseError
+         if errors.As(err, &pe) {
+            msg = (&note.ParseError{Line: pe.Line, Err: pe.Err}).Error()
+         }
+         problems = append(problems, Problem{Kind: ParseError, Path: f, Message: msg})
+         // Still count the ID from the filename as existing, so its tags aren't reported as referencing nothing
+         notes[note.IDFromFileName(f)] = f
+         continue
+      }
+      if other, exists := notes[n.ID]; exists {
+         problems = append(problems, Problem{
+            Kind: DuplicateID,
+            Path: f,
+            Message: fmt.Sprintf("note ID %s is also used by %s", n.ID, other),
+         })
+         continue
+      }
+      notes[n.ID] = f
+      parsed = append(parsed, n)
+      problems = append(problems, checkNote(tm, f, &n)...)
+   }
+   // Links can only be checked once every note ID is known
+   ids := sets.KeySet(notes)
+   for _, n := range parsed {
+      problems = append(problems, checkLinks(ids, notes[n.ID], &n)...)
+   }
+   problems = append(problems, checkTags(tm, notes)...)
+   return
+}
+
+
+// checkNote checks a single note's filename, and that its tags all exist and list it.
+func checkNote(tm tags.TagMap, path string, n *note.Note) (problems []Problem) {
+   if want := n.GenFileName(); filepath.Base(path) != want {
+      dest := filepath.Join(filepath.Dir(path), want)
+      problems = append(problems, Problem{
+         Kind: FileName,
+         Path: path,
+         Message: "should be named " + want,
+         fix: func() error {
+            if _, err := os.Stat(dest); err == nil {
+               return fmt.Errorf("cannot rename %s, %s already exists", path, dest)
+            }
+            return os.Rename(path, dest)
+         },
+      })
+   }
+
+   for _, name := range sets.List(n.Tags) {
+      tag := tm.Get(name)
+      switch {
+         case tag != nil && tag.Virtual:
+            problems = append(problems, Problem{
+               Kind: VirtualTag,
+               Path: path,
+               Message: fmt.Sprintf("tag %q is virtual, so can't be given to notes directly", tag.Name),
+            })
+         case tag == nil:
+            problems = append(problems, Problem{
+               Kind: MissingTag,
+               Path: path,
+               Message: fmt.Sprintf("tag %q has no tag file", name),
+               fix: retagFix(n.ID, name),
+            })
+         case !tag.Notes.Has(n.ID):
+            problems = append(problems, Problem{
+               Kind: UnindexedNote,
+               Path: path,
+               Message: fmt.Sprintf("tag %q does not list note %s", tag.Name, n.ID),
+               fix: retagFix(n.ID, name),
+            })
+      }
+   }
+   return
+}
+
+
+// checkLinks checks that every note linked to from a note exists; links may be a prefix of the ID, but then it must
+// only match the one note. Broken links can't be fixed automatically, as there's no telling what the link should have
+// pointed to.
+func checkLinks(ids sets.Set[string], path string, n *note.Note) (problems []Problem) {
+   for _, id := range n.Links() {
+      _, err := note.ResolveID(ids, id)
+      var ambiguous *note.AmbiguousIDError
+      switch {
+         case err == nil:
+            continue
+         case errors.As(err, &ambiguous):
+            problems = append(problems, Problem{
+               Kind: BrokenLink,
+               Path: path,
+               Message: fmt.Sprintf("links to note %s, which is ambiguous between %s",
+                  id, strings.Join(ambiguous.Candidates, ", ")),
+            })
+         default:
+            problems = append(problems, Problem{
+               Kind: BrokenLink,
+               Path: path,
+               Message: fmt.Sprintf("links to note %s, which does not exist", id),
+            })
+      }
+   }
+   return
+}
+
+
+// checkTags checks that every note ID listed in every tag file exists.
+func checkTags(tm tags.TagMap, notes map[string]string) (problems []Problem) {
+   seen := sets.New[*tags.Tag]()
+   for _, tag := range tm {
+      if seen.Has(tag) {
+         continue
+      }
+      seen.Insert(tag)
+      for _, id := range sets.List(tag.Notes) {
+         if _, exists := notes[id]; exists {
+            continue
+         }
+         name, id := tag.Name, id
+         path, err := tag.Path()
+         if err != nil {
+            path = tag.Name
+         }
+         problems = append(problems, Problem{
+            Kind: MissingNote,
+            Path: path,
+            Message: fmt.Sprintf("tag %q lists note %s, which does not exist", name, id),
+            fix: func() error {
+               return tags.Retag(id, sets.New(name), nil)
+            },
+         })
+      }
+   }
+   slices.SortFunc(problems, func(a, b Problem) int {
+      return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Message, b.Message))
+   })
+   return
+}
+
+
+// retagFix returns a fix adding a note to a tag, creating the tag if necessary.
+func retagFix(id, name string) func() error {
+   return func() error {
+      return tags.Retag(id, nil, sets.New(name))
+   }
+}
@@ -0,0 +1,84 @@
+package fsck
+
+import (
+   "os"
+   "path/filepath"
+   "testing"
+
+   "github.com/omnikron13/zelkata/internal/testenv"
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_Check(t *testing.T) {
+   testenv.Setup(t)
+   // A healthy note, tagged properly
+   good := note.New("Good.\n")
+   good.Tags = sets.New("Good")
+   if err := good.Save(); err != nil {
+      t.Fatalf("Failed to save note: %s", err)
+   }
+   if err := tags.Retag(good.ID, nil, good.Tags); err != nil {
+      t.Fatalf("Failed to tag note: %s", err)
+   }
+
+   // A note whose tags were never saved, under the wrong filename, with two good links (one by ID prefix) and one broken
+   misnamed := note.New("Misnamed, see [[" + good.ID + "]], [[" + good.ID[:8] + "]] and [[GONE]].\n")
+   misnamed.Tags = sets.New("Good", "Forgotten")
+   misnamedPath := filepath.Join(paths.Notes(), "misnamed." + misnamed.ID + ".md")
+   if err := misnamed.SaveAs(misnamedPath); err != nil {
+      t.Fatalf("Failed to save note: %s", err)
+   }
+
+   // A tag referencing a note which doesn't exist, in a hand-written tag file, and a note which can't be parsed
+   if err := tags.Retag("GHOST", nil, sets.New("Good")); err != nil {
+      t.Fatalf("Failed to tag note: %s", err)
+   }
+   tm, err := tags.LoadAll()
+   if err != nil {
+      t.Fatalf("Failed to load tags: %s", err)
+   }
+   generatedPath, _ := tm.Get("Good").Path()
+   tagPath := filepath.Join(paths.Tags(), "good.tag.yaml")
+   if err := os.Rename(generatedPath, tagPath); err != nil {
+      t.Fatalf("Failed to move tag file: %s", err)
+   }
+   brokenPath := filepath.Join(paths.Notes(), "broken.md")
+   if err := os.WriteFile(brokenPath, []byte("---\nid: [\n...\n\nBroken.\n"), 0600); err != nil {
+      t.Fatalf("Failed to write note: %s", err)
+   }
+
+   problems, err := Check()
+   assert.Nil(t, err)
+   kinds := map[Kind]int{}
+   for _, p := range problems {
+      kinds[p.Kind]++
+   }
+   assert.Equal(t, map[Kind]int{ParseError: 1, FileName: 1, MissingTag: 1, UnindexedNote: 1, MissingNote: 1, BrokenLink: 1}, kinds)
+   for _, p := range problems {
+      if p.Kind == MissingNote {
+         assert.Equal(t, tagPath, p.Path)
+      }
+   }
+
+   for _, p := range problems {
+      if p.Fixable() {
+         assert.Nil(t, p.Fix(), p.String())
+      }
+   }
+   problems, err = Check()
+   assert.Nil(t, err)
+   if assert.Len(t, problems, 2) {
+      for _, p := range problems {
+         assert.Contains(t, []Kind{ParseError, BrokenLink}, p.Kind)
+         assert.False(t, p.Fixable())
+      }
+   }
+   assert.FileExists(t, filepath.Join(paths.Notes(), misnamed.GenFileName()))
+   assert.NoFileExists(t, generatedPath)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "fmt"
+   "time"
+
+   "github.com/omnikron13/zelkata/history"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// historyCmd prints the revision log of a note; the time and content hash of each save, oldest first.
+func historyCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 1 {
+      return errors.New("history requires exactly one note ID")
+   }
+   n, err := readNoteByID(cmd.Args().First())
+   if err != nil {
+      return err
+   }
+   revs, err := history.Load(n.ID)
+   if err != nil {
+      return err
+   }
+   for _, r := range revs {
+      fmt.Printf("%s\t%s\n", r.Time.Local().Format(time.DateTime), r.Hash)
+   }
+   return nil
+}
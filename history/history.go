@@ -0,0 +1,87 @@
+// Package history keeps a log of the revisions of each note; when it was saved, and a hash of what was saved. Unlike
+// the search & links indices this can't be rebuilt from the notes themselves, so the logs live in the data directory,
+// one YAML file per note alongside (rather than inside) the note files so that they don't clutter the front matter.
+package history
+
+import (
+   "crypto/sha256"
+   "encoding/hex"
+   "errors"
+   "os"
+   "path/filepath"
+   "time"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+
+   "gopkg.in/yaml.v3"
+)
+
+
+// Revision is a single entry in a note's revision log.
+type Revision struct {
+   // Time is when the revision was saved.
+   Time time.Time `yaml:"time"`
+
+   // Hash is the hex encoded SHA-256 hash of the note file as it was saved.
+   Hash string `yaml:"sha256"`
+}
+
+
+// logPath returns the path of the revision log for the note with the given ID.
+func logPath(id string) string {
+   return filepath.Join(paths.Revisions(), id + ".yaml")
+}
+
+
+// Load reads the revision log of the note with the given ID, oldest first. A note with no log yet simply has no
+// revisions.
+func Load(id string) (revs []Revision, err error) {
+   b, err := os.ReadFile(logPath(id))
+   if errors.Is(err, os.ErrNotExist) {
+      return nil, nil
+   }
+   if err != nil {
+      return nil, err
+   }
+   err = yaml.Unmarshal(b, &revs)
+   return
+}
+
+
+// Record appends a revision for each of the notes as they are now to their revision logs. It has the signature of a
+// note.SaveHook so that every save is recorded.
+func Record(notes ...*note.Note) error {
+   for _, n := range notes {
+      if err := record(n); err != nil {
+         return err
+      }
+   }
+   return nil
+}
+
+
+// record appends a revision for a single note to its revision log.
+func record(n *note.Note) error {
+   b, err := n.Marshal()
+   if err != nil {
+      return err
+   }
+   sum := sha256.Sum256(b)
+   rev := Revision{Time: n.LastModified(), Hash: hex.EncodeToString(sum[:])}
+
+   // A log is a YAML sequence, so a revision can be appended as a single item sequence without rewriting the rest
+   entry, err := yaml.Marshal([]Revision{rev})
+   if err != nil {
+      return err
+   }
+   f, err := os.OpenFile(logPath(n.ID), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+   if err != nil {
+      return err
+   }
+   if _, err := f.Write(entry); err != nil {
+      f.Close()
+      return err
+   }
+   return f.Close()
+}
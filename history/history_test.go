@@ -0,0 +1,49 @@
+package history
+
+import (
+   "os"
+   "testing"
+
+   "github.com/omnikron13/zelkata/internal/testenv"
+   "github.com/omnikron13/zelkata/note"
+
+   "github.com/stretchr/testify/assert"
+)
+
+
+// TestMain registers Record as a save hook, as it would be by the zelkata command.
+func TestMain(m *testing.M) {
+   note.RegisterSaveHook(Record)
+   os.Exit(m.Run())
+}
+
+
+func Test_Record(t *testing.T) {
+   testenv.Setup(t)
+   n := note.New("First.\n")
+   revs, err := Load(n.ID)
+   assert.Nil(t, err)
+   assert.Empty(t, revs)
+
+   if err := n.Save(); err != nil {
+      t.Fatalf("Failed to save note: %s", err)
+   }
+   assert.Nil(t, n.Modified)
+
+   n.Body = "Second.\n"
+   if err := n.Save(); err != nil {
+      t.Fatalf("Failed to save note: %s", err)
+   }
+   if assert.NotNil(t, n.Modified) {
+      assert.False(t, n.Modified.Before(n.Created))
+   }
+
+   revs, err = Load(n.ID)
+   assert.Nil(t, err)
+   if assert.Len(t, revs, 2) {
+      assert.True(t, revs[0].Time.Equal(n.Created))
+      assert.True(t, revs[1].Time.Equal(*n.Modified))
+      assert.Len(t, revs[0].Hash, 64)
+      assert.NotEqual(t, revs[0].Hash, revs[1].Hash)
+   }
+}
@@ -0,0 +1,29 @@
+// Package testenv gives tests which necessarily work on real files (notes, tags, drafts, journals, etc.) a data and
+// state directory of their own, so that they never touch the real ones nor each other's.
+package testenv
+
+import (
+   "testing"
+
+   "github.com/omnikron13/zelkata/paths"
+
+   "github.com/adrg/xdg"
+)
+
+
+// Setup points the data and state directories at fresh temporary directories for the rest of the test, putting them
+// back as they were once it is done.
+func Setup(t testing.TB) {
+   // Registered first so that it runs last, once Setenv has restored the environment
+   t.Cleanup(reload)
+   t.Setenv("XDG_DATA_HOME", t.TempDir())
+   t.Setenv("XDG_STATE_HOME", t.TempDir())
+   reload()
+}
+
+
+// reload picks up the XDG directories from the environment again, and forgets any paths derived from the old ones.
+func reload() {
+   xdg.Reload()
+   paths.Reset()
+}
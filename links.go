@@ -0,0 +1,77 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "fmt"
+
+   "github.com/omnikron13/zelkata/links"
+   "github.com/omnikron13/zelkata/note"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// linksCmd lists the notes linked to from a note. The links are read straight from the note rather than the index, so
+// are always current; links to notes which don't exist are listed as missing.
+func linksCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 1 {
+      return errors.New("links requires exactly one note ID")
+   }
+   n, err := readNoteByID(cmd.Args().First())
+   if err != nil {
+      return err
+   }
+   for _, id := range n.Links() {
+      path, err := note.FindByID(id)
+      if err != nil {
+         fmt.Printf("%s\t(missing)\n", id)
+         continue
+      }
+      target, err := note.ReadFile(path)
+      if err != nil {
+         return err
+      }
+      printNotes(nil, []note.Note{target})
+   }
+   return nil
+}
+
+
+// backlinksCmd lists the notes which link to a note, according to the link index.
+func backlinksCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 1 {
+      return errors.New("backlinks requires exactly one note ID")
+   }
+   n, err := readNoteByID(cmd.Args().First())
+   if err != nil {
+      return err
+   }
+   ix, err := links.Load()
+   if err != nil {
+      return err
+   }
+   for _, id := range ix.Backlinks(n.ID) {
+      // The index can lag behind notes being removed, so anything which no longer exists is just skipped over
+      path, err := note.FindByID(id)
+      if err != nil {
+         continue
+      }
+      src, err := note.ReadFile(path)
+      if err != nil {
+         return err
+      }
+      printNotes(nil, []note.Note{src})
+   }
+   return nil
+}
+
+
+// readNoteByID resolves a full or partial note ID and reads the note.
+func readNoteByID(id string) (note.Note, error) {
+   path, err := note.FindByID(id)
+   if err != nil {
+      return note.Note{}, err
+   }
+   return note.ReadFile(path)
+}
@@ -0,0 +1,159 @@
+// Package links maintains an index of the wikilink-style links between notes, so that the notes linking to any given
+// note (its backlinks) can be found without reading every note. Like the search index it is derived data, rebuilt from
+// the note files whenever necessary, so it lives in the state directory.
+package links
+
+import (
+   "bytes"
+   "encoding/gob"
+   "errors"
+   "os"
+   "path/filepath"
+   "slices"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/txn"
+
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+// Index maps the ID of each note to the IDs of the notes it links to.
+type Index struct {
+   // Links maps note IDs to the IDs linked to from the note, as they were written in the note.
+   Links map[string][]string
+}
+
+
+// New returns a new empty Index.
+func New() *Index {
+   return &Index{Links: map[string][]string{}}
+}
+
+
+// indexPath returns the path of the on-disk index.
+func indexPath() string {
+   return filepath.Join(paths.State(), "links.idx")
+}
+
+
+// Load reads the on-disk index, returning a new empty Index if there isn't one yet.
+func Load() (*Index, error) {
+   f, err := os.Open(indexPath())
+   if errors.Is(err, os.ErrNotExist) {
+      return New(), nil
+   }
+   if err != nil {
+      return nil, err
+   }
+   defer f.Close()
+   ix := New()
+   if err := gob.NewDecoder(f).Decode(ix); err != nil {
+      return nil, err
+   }
+   return ix, nil
+}
+
+
+// Save writes the index to disk, atomically so that an interrupted save never leaves a truncated index.
+func (ix *Index) Save() error {
+   var b bytes.Buffer
+   if err := gob.NewEncoder(&b).Encode(ix); err != nil {
+      return err
+   }
+   return txn.WriteFile(indexPath(), b.Bytes(), 0600)
+}
+
+
+// Update adds (or re-adds) notes to the on-disk index. It has the signature of a note.SaveHook so that the index is
+// kept up to date as notes are saved.
+func Update(notes ...*note.Note) error {
+   ix, err := Load()
+   if err != nil {
+      return err
+   }
+   ids, err := note.IDs()
+   if err != nil {
+      return err
+   }
+   for _, n := range notes {
+      ids.Insert(n.ID)
+   }
+   for _, n := range notes {
+      ix.Add(n, ids)
+   }
+   return ix.Save()
+}
+
+
+// Rebuild creates a new index from scratch from all the note files, and saves it.
+func Rebuild() (*Index, error) {
+   files, err := note.Files()
+   if err != nil {
+      return nil, err
+   }
+   notes := make([]note.Note, 0, len(files))
+   ids := sets.New[string]()
+   for _, f := range files {
+      n, err := note.ReadFile(f)
+      if err != nil {
+         return nil, err
+      }
+      notes = append(notes, n)
+      ids.Insert(n.ID)
+   }
+   // Links can only be resolved once every note ID is known
+   ix := New()
+   for _, n := range notes {
+      ix.Add(&n, ids)
+   }
+   return ix, ix.Save()
+}
+
+
+// Add records the links in a note, replacing anything previously recorded for the same ID. Links may be written as a
+// prefix of the ID, like any other note ID, so each is recorded as the full ID it resolves to among ids, the IDs of all
+// the notes; those which don't resolve (e.g. to a note which doesn't exist yet) are recorded as written.
+func (ix *Index) Add(n *note.Note, ids sets.Set[string]) {
+   if links := n.Links(); len(links) > 0 {
+      resolved := make([]string, 0, len(links))
+      for _, l := range links {
+         if full, err := note.ResolveID(ids, l); err == nil {
+            l = full
+         }
+         // The same note may well be linked to by both its full ID and a prefix
+         if !slices.Contains(resolved, l) {
+            resolved = append(resolved, l)
+         }
+      }
+      ix.Links[n.ID] = resolved
+   } else {
+      delete(ix.Links, n.ID)
+   }
+}
+
+
+// Remove drops a note's links from the index, if it's there. Links to the note from other notes are left alone, as
+// they are still in those notes.
+func (ix *Index) Remove(id string) {
+   delete(ix.Links, id)
+}
+
+
+// Forward returns the IDs linked to from a note.
+func (ix *Index) Forward(id string) []string {
+   return ix.Links[id]
+}
+
+
+// Backlinks returns the IDs of the notes which link to a note, sorted.
+func (ix *Index) Backlinks(id string) (ids []string) {
+   for src, targets := range ix.Links {
+      if slices.Contains(targets, id) {
+         ids = append(ids, src)
+      }
+   }
+   slices.Sort(ids)
+   return
+}
@@ -0,0 +1,45 @@
+package links
+
+import (
+   "testing"
+
+   "github.com/omnikron13/zelkata/note"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_Index(t *testing.T) {
+   ix := New()
+   ids := sets.New("A", "B", "C")
+   ix.Add(&note.Note{Meta: note.Meta{ID: "A"}, Body: "Links to [[B]] and [[C|see C]]."}, ids)
+   ix.Add(&note.Note{Meta: note.Meta{ID: "B"}, Body: "Links back to [[A]], and to [[C]]."}, ids)
+   ix.Add(&note.Note{Meta: note.Meta{ID: "C"}, Body: "No links."}, ids)
+
+   assert.Equal(t, []string{"B", "C"}, ix.Forward("A"))
+   assert.Empty(t, ix.Forward("C"))
+   assert.Equal(t, []string{"A", "B"}, ix.Backlinks("C"))
+   assert.Equal(t, []string{"B"}, ix.Backlinks("A"))
+
+   t.Run("re-add replaces", func(t *testing.T) {
+      ix.Add(&note.Note{Meta: note.Meta{ID: "B"}, Body: "No more links."}, ids)
+      assert.Equal(t, []string{"A"}, ix.Backlinks("C"))
+      assert.NotContains(t, ix.Links, "B")
+   })
+
+   t.Run("remove", func(t *testing.T) {
+      ix.Remove("A")
+      assert.Empty(t, ix.Backlinks("C"))
+   })
+
+   t.Run("prefix links", func(t *testing.T) {
+      ids := sets.New("QWERTY", "QWASDF", "ZXCVBN")
+      ix := New()
+      ix.Add(&note.Note{Meta: note.Meta{ID: "ZXCVBN"}, Body: "See [[QWE]], [[QWERTY]] again, [[QW]] and [[NOPE]]."}, ids)
+      // Unambiguous prefixes are resolved (and then deduplicated), anything else is kept as written
+      assert.Equal(t, []string{"QWERTY", "QW", "NOPE"}, ix.Forward("ZXCVBN"))
+      assert.Equal(t, []string{"ZXCVBN"}, ix.Backlinks("QWERTY"))
+      assert.Empty(t, ix.Backlinks("QWASDF"))
+   })
+}
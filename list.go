@@ -0,0 +1,114 @@
+package main
+
+import (
+   "context"
+   "fmt"
+   "slices"
+   "strings"
+   "time"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/urfave/cli/v3"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+// listCmd prints a line for each note in the catalogue; ID, created date, title, and tags, separated by tabs so that
+// the output can be easily chewed on by cut, awk, etc.
+func listCmd(ctx context.Context, cmd *cli.Command) error {
+   notes, err := note.ReadAll()
+   if err != nil {
+      return err
+   }
+
+   // Virtual tags are needed both for filtering and to be listed alongside the tags the notes actually have
+   tm, err := tags.LoadAll()
+   if err != nil {
+      return err
+   }
+   tm.ApplyVirtual(notes)
+
+   // Filter down to only notes which have _all_ of the requested tags
+   if want := cmd.StringSlice("tag"); len(want) > 0 {
+      notes = slices.DeleteFunc(notes, func(n note.Note) bool {
+         for _, w := range want {
+            if !hasTag(tm, &n, w, cmd.Bool("recursive")) {
+               return true
+            }
+         }
+         return false
+      })
+   }
+
+   // Or only those with no tags at all, which need triaging
+   if cmd.Bool("untagged") {
+      notes = slices.DeleteFunc(notes, func(n note.Note) bool { return len(n.Tags) > 0 })
+   }
+
+   switch cmd.String("sort") {
+      case "created":
+         slices.SortStableFunc(notes, func(a, b note.Note) int { return a.Created.Compare(b.Created) })
+      case "modified":
+         slices.SortStableFunc(notes, func(a, b note.Note) int { return a.LastModified().Compare(b.LastModified()) })
+      case "id":
+         slices.SortStableFunc(notes, func(a, b note.Note) int { return strings.Compare(a.ID, b.ID) })
+      case "title":
+         slices.SortStableFunc(notes, func(a, b note.Note) int {
+            return strings.Compare(strings.ToLower(a.DisplayTitle()), strings.ToLower(b.DisplayTitle()))
+         })
+      default:
+         return fmt.Errorf("unsupported sort order: %s", cmd.String("sort"))
+   }
+   if cmd.Bool("reverse") {
+      slices.Reverse(notes)
+   }
+
+   if limit := int(cmd.Int("limit")); limit > 0 && limit < len(notes) {
+      notes = notes[:limit]
+   }
+
+   printNotes(tm, notes)
+   return nil
+}
+
+
+// printNotes prints a tab separated line for each note; ID, created date, title, and tags. The tags include any virtual
+// tags applying to the note, if the TagMap is given and has had its virtual tags applied.
+func printNotes(tm tags.TagMap, notes []note.Note) {
+   for _, n := range notes {
+      fmt.Printf("%s\t%s\t%s\t%s\n",
+         n.ID,
+         n.Created.Format(time.DateTime),
+         n.DisplayTitle(),
+         strings.Join(tm.NoteTags(&n), ","),
+      )
+   }
+}
+
+
+// hasTag reports whether the note has the named tag, or optionally any of its descendants in the tag hierarchy. Names
+// are resolved through the TagMap so that aliases match the tag they point to, falling back to a case-insensitive
+// comparison for tags that don't (yet) have a tag file.
+func hasTag(tm tags.TagMap, n *note.Note, name string, recursive bool) bool {
+   want := sets.New[*tags.Tag]()
+   if t := tm.Get(name); t != nil {
+      want.Insert(t)
+      if recursive {
+         want.Insert(tm.Descendants(name)...)
+      }
+   }
+   for t := range n.Tags {
+      if want.Has(tm.Get(t)) || strings.EqualFold(t, name) {
+         return true
+      }
+   }
+   // Virtual tags aren't listed by the notes they apply to, only the other way around
+   for t := range want {
+      if t.Virtual && t.Notes.Has(n.ID) {
+         return true
+      }
+   }
+   return false
+}
@@ -0,0 +1,91 @@
+// Package lock provides an advisory lock on the data directory, so that several Zelkata processes running at once
+// (e.g. `zelkata add` in two terminals, or the TUI alongside a CLI command) don't trample each other's changes. Any
+// number of processes can hold the lock in Shared mode to read, but only one can hold it in Exclusive mode to write,
+// and not while anything else holds it at all.
+package lock
+
+import (
+   "errors"
+   "fmt"
+   "os"
+   "path/filepath"
+   "time"
+
+   "github.com/omnikron13/zelkata/config"
+   "github.com/omnikron13/zelkata/paths"
+)
+
+
+// Mode is the mode a Lock is held in.
+type Mode int
+
+const (
+   // Shared is for reading, and can be held by any number of processes at once.
+   Shared Mode = iota
+   // Exclusive is for writing, and can only be held by one process, while no others hold the lock in either mode.
+   Exclusive
+)
+
+
+// ErrLocked is returned when the lock couldn't be acquired before timing out.
+var ErrLocked = errors.New("the data directory is locked by another zelkata process")
+
+
+// pollInterval is how often to retry acquiring the lock while waiting for it.
+const pollInterval = 50 * time.Millisecond
+
+
+// Lock is a held lock on the data directory.
+type Lock struct {
+   f *os.File
+}
+
+
+// Acquire takes the data directory lock in the given mode, waiting as long as the configured lock.timeout for any
+// other process holding it to finish.
+func Acquire(mode Mode) (*Lock, error) {
+   timeout, err := time.ParseDuration(config.GetOrPanic[string]("lock.timeout"))
+   if err != nil {
+      return nil, fmt.Errorf("invalid lock.timeout: %w", err)
+   }
+   return acquire(filepath.Join(paths.Data(), ".lock"), mode, timeout)
+}
+
+
+// TryAcquire takes the lock on the lock file at path in the given mode, without waiting at all; ErrLocked is returned
+// straight away if another process holds it. This is for locking something narrower than the whole data directory,
+// e.g. a single draft, which is either free or busy for far longer than would be worth waiting.
+func TryAcquire(path string, mode Mode) (*Lock, error) {
+   return acquire(path, mode, 0)
+}
+
+
+// acquire takes the lock on the lock file at path, retrying until the timeout runs out.
+func acquire(path string, mode Mode, timeout time.Duration) (*Lock, error) {
+   f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+   if err != nil {
+      return nil, err
+   }
+   deadline := time.Now().Add(timeout)
+   for {
+      ok, err := tryLock(f, mode)
+      if err != nil {
+         f.Close()
+         return nil, err
+      }
+      if ok {
+         return &Lock{f: f}, nil
+      }
+      if time.Now().After(deadline) {
+         f.Close()
+         return nil, fmt.Errorf("%w (gave up after %s)", ErrLocked, timeout)
+      }
+      time.Sleep(pollInterval)
+   }
+}
+
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+   return errors.Join(unlock(l.f), l.f.Close())
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package lock
+
+import (
+   "os"
+)
+
+
+// tryLock always succeeds where flock isn't available, so locking is a no-op there rather than stopping Zelkata from
+// working entirely.
+func tryLock(f *os.File, mode Mode) (bool, error) {
+   return true, nil
+}
+
+
+// unlock has nothing to release where flock isn't available.
+func unlock(f *os.File) error {
+   return nil
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package lock
+
+import (
+   "errors"
+   "path/filepath"
+   "testing"
+   "time"
+
+   "github.com/stretchr/testify/assert"
+)
+
+
+func Test_acquire(t *testing.T) {
+   // flock locks belong to the open file, so separate opens within one process contend just as separate processes do
+   path := filepath.Join(t.TempDir(), ".lock")
+
+   a, err := acquire(path, Shared, 0)
+   assert.Nil(t, err)
+   b, err := acquire(path, Shared, 0)
+   assert.Nil(t, err, "shared locks should not contend with each other")
+
+   _, err = acquire(path, Exclusive, 100 * time.Millisecond)
+   assert.True(t, errors.Is(err, ErrLocked))
+
+   assert.Nil(t, a.Release())
+   assert.Nil(t, b.Release())
+
+   x, err := acquire(path, Exclusive, 0)
+   assert.Nil(t, err)
+   _, err = acquire(path, Shared, 0)
+   assert.True(t, errors.Is(err, ErrLocked))
+
+   // Waiting should succeed once the holder lets go
+   go func() {
+      time.Sleep(100 * time.Millisecond)
+      x.Release()
+   }()
+   y, err := acquire(path, Exclusive, 5 * time.Second)
+   if assert.Nil(t, err) {
+      assert.Nil(t, y.Release())
+   }
+}
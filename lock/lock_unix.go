@@ -0,0 +1,37 @@
+//go:build unix
+
+package lock
+
+import (
+   "errors"
+   "os"
+   "syscall"
+)
+
+
+// tryLock attempts to flock the file in the given mode without blocking, reporting whether it succeeded.
+func tryLock(f *os.File, mode Mode) (bool, error) {
+   how := syscall.LOCK_SH
+   if mode == Exclusive {
+      how = syscall.LOCK_EX
+   }
+   for {
+      err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB)
+      switch {
+         case err == nil:
+            return true, nil
+         case errors.Is(err, syscall.EWOULDBLOCK):
+            return false, nil
+         case errors.Is(err, syscall.EINTR):
+            continue
+         default:
+            return false, err
+      }
+   }
+}
+
+
+// unlock releases the flock on the file.
+func unlock(f *os.File) error {
+   return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+   "context"
+   "errors"
+
+   "github.com/omnikron13/zelkata/lock"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// locked wraps a command's action so that it runs holding the data directory lock in the given mode; Shared for
+// commands which only read the notes and tags, Exclusive for those which change them. Commands which spend a long time
+// waiting on the user (e.g. add) should instead only take the lock around the actual changes, with withLock.
+func locked(mode lock.Mode, action cli.ActionFunc) cli.ActionFunc {
+   return func(ctx context.Context, cmd *cli.Command) error {
+      return withLock(mode, func() error { return action(ctx, cmd) })
+   }
+}
+
+
+// withLock runs f holding the data directory lock in the given mode.
+func withLock(mode lock.Mode, f func() error) (err error) {
+   l, err := lock.Acquire(mode)
+   if err != nil {
+      return err
+   }
+   defer func() { err = errors.Join(err, l.Release()) }()
+   return f()
+}
@@ -5,9 +5,16 @@ package main
 
 import (
    "context"
+   "fmt"
    "os"
 
+   "github.com/omnikron13/zelkata/history"
+   "github.com/omnikron13/zelkata/links"
+   "github.com/omnikron13/zelkata/lock"
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/search"
    "github.com/omnikron13/zelkata/tui"
+   "github.com/omnikron13/zelkata/txn"
 
    "github.com/urfave/cli/v3"
 )
@@ -17,6 +24,23 @@ import (
 // a temporary arrangement, as I'm unsure how much overlap there is likely to be be with my ultimate plan of a modern
 // TUI rather than a 'flags and subcommands' model. I may maintain both though, along with web interfaces, apps, etc.
 func main() {
+   // Keep the derived indexes up to date as notes are saved
+   note.RegisterSaveHook(search.Update)
+   note.RegisterSaveHook(links.Update)
+   note.RegisterSaveHook(history.Record)
+
+   // Finish off anything left half done by a previous run which was interrupted, before anything else is touched. Any
+   // journal left by a process which is still running is protected by it holding the lock. Usually there's nothing to
+   // finish, and then the lock isn't taken at all, so as not to hold up other processes just to find that out.
+   pending, err := txn.Pending()
+   if err == nil && pending {
+      err = withLock(lock.Exclusive, txn.Recover)
+   }
+   if err != nil {
+      fmt.Fprintln(os.Stderr, err)
+      os.Exit(1)
+   }
+
    cmd:= &cli.Command{
       Name:  "Zelkata",
       Usage: "add notes and stuff",
@@ -26,16 +50,339 @@ func main() {
             Name: "add",
             Aliases: []string{"a"},
             Usage: "add a note",
+            ArgsUsage: "[-]",
             Action: addCmd,
+            Flags: []cli.Flag{
+               &cli.StringFlag{
+                  Name: "template",
+                  Aliases: []string{"T"},
+                  Usage: "start the note from the named template",
+               },
+               &cli.StringSliceFlag{
+                  Name: "set",
+                  Usage: "answer a template prompt as `LABEL=VALUE`, rather than being asked (can be repeated)",
+               },
+               &cli.StringSliceFlag{
+                  Name: "tag",
+                  Aliases: []string{"t"},
+                  Usage: "add a tag to the note (can be repeated)",
+               },
+               &cli.StringFlag{
+                  Name: "title",
+                  Usage: "give the note an explicit title",
+               },
+               &cli.StringFlag{
+                  Name: "body",
+                  Aliases: []string{"b"},
+                  Usage: "use this as the note body, rather than opening an editor",
+               },
+               &cli.BoolFlag{
+                  Name: "json",
+                  Usage: "describe the new note as JSON, rather than just printing its ID",
+               },
+            },
+         },
+         {
+            Name: "backlinks",
+            Usage: "list the notes linking to a note",
+            ArgsUsage: "<id>",
+            Action: locked(lock.Shared, backlinksCmd),
+         },
+         {
+            Name: "drafts",
+            Usage: "manage drafts of notes which were never finished being added",
+            Action: draftsLsCmd,
+            Commands: []*cli.Command{
+               {
+                  Name: "ls",
+                  Aliases: []string{"list"},
+                  Usage: "list the drafts",
+                  Action: draftsLsCmd,
+               },
+               {
+                  Name: "resume",
+                  Usage: "carry on adding a note from a draft",
+                  ArgsUsage: "<id>",
+                  Action: draftsResumeCmd,
+               },
+               {
+                  Name: "discard",
+                  Aliases: []string{"rm"},
+                  Usage: "throw away a draft",
+                  ArgsUsage: "<id>",
+                  Action: draftsDiscardCmd,
+               },
+            },
+         },
+         {
+            Name: "edit",
+            Aliases: []string{"e"},
+            Usage: "edit an existing note",
+            ArgsUsage: "<id>",
+            Action: editCmd,
+         },
+         {
+            Name: "fsck",
+            Usage: "check the notes and tags for problems",
+            Action: fsckCmd,
+            Flags: []cli.Flag{
+               &cli.BoolFlag{
+                  Name: "fix",
+                  Usage: "repair any problems which can be fixed automatically",
+               },
+            },
+         },
+         {
+            Name: "history",
+            Usage: "list the revisions of a note",
+            ArgsUsage: "<id>",
+            Action: locked(lock.Shared, historyCmd),
+         },
+         {
+            Name: "links",
+            Usage: "list the notes a note links to",
+            ArgsUsage: "<id>",
+            Action: locked(lock.Shared, linksCmd),
+         },
+         {
+            Name: "list",
+            Aliases: []string{"ls"},
+            Usage: "list notes",
+            Action: locked(lock.Shared, listCmd),
+            Flags: []cli.Flag{
+               &cli.StringSliceFlag{
+                  Name: "tag",
+                  Aliases: []string{"t"},
+                  Usage: "only list notes with this tag (may be repeated; all must match)",
+               },
+               &cli.BoolFlag{
+                  Name: "recursive",
+                  Aliases: []string{"R"},
+                  Usage: "also match notes with any descendant of the given tags",
+               },
+               &cli.BoolFlag{
+                  Name: "untagged",
+                  Aliases: []string{"u"},
+                  Usage: "only list notes without any tags",
+               },
+               &cli.StringFlag{
+                  Name: "sort",
+                  Aliases: []string{"s"},
+                  Usage: "sort notes by `FIELD`; created, modified, id, or title",
+                  Value: "created",
+               },
+               &cli.BoolFlag{
+                  Name: "reverse",
+                  Aliases: []string{"r"},
+                  Usage: "reverse the sort order",
+               },
+               &cli.IntFlag{
+                  Name: "limit",
+                  Aliases: []string{"n"},
+                  Usage: "list at most `N` notes",
+               },
+            },
+         },
+         {
+            Name: "migrate",
+            Usage: "update the notes after changing how they are stored",
+            Commands: []*cli.Command{
+               {
+                  Name: "dates",
+                  Usage: "rewrite the dates of every note in a new format",
+                  Action: locked(lock.Exclusive, migrateDatesCmd),
+                  Flags: []cli.Flag{
+                     &cli.StringFlag{
+                        Name: "to",
+                        Usage: "the `FORMAT` to write dates in; a Go time layout name (e.g. RFC3339), or a custom layout",
+                        Required: true,
+                     },
+                  },
+               },
+            },
+         },
+         {
+            Name: "query",
+            Aliases: []string{"q"},
+            Usage: "list notes matching a boolean tag query, e.g. 'music AND (guitar OR banjo) AND NOT draft'",
+            ArgsUsage: "<expression>",
+            Action: locked(lock.Shared, queryCmd),
+            Flags: []cli.Flag{
+               &cli.BoolFlag{
+                  Name: "recursive",
+                  Aliases: []string{"R"},
+                  Usage: "tags also match notes with any of their descendant tags",
+               },
+            },
+         },
+         {
+            Name: "ref",
+            Usage: "manage the refs of a note",
+            Commands: []*cli.Command{
+               {
+                  Name: "add",
+                  Usage: "add a ref to a note; a URL, file path, ISBN/DOI, note:<id> or tag:<name>",
+                  ArgsUsage: "<id> <name> <ref>",
+                  Action: locked(lock.Exclusive, refAddCmd),
+               },
+               {
+                  Name: "ls",
+                  Aliases: []string{"list"},
+                  Usage: "list the refs of a note",
+                  ArgsUsage: "<id>",
+                  Action: locked(lock.Shared, refLsCmd),
+               },
+               {
+                  Name: "check",
+                  Usage: "check that the refs of notes are valid and still resolve",
+                  ArgsUsage: "[id...]",
+                  Action: locked(lock.Shared, refCheckCmd),
+               },
+            },
+         },
+         {
+            Name: "reindex",
+            Usage: "rebuild the tag, search and link indexes from the note files",
+            Action: locked(lock.Exclusive, reindexCmd),
+         },
+         {
+            Name: "rm",
+            Usage: "move notes to the trash",
+            ArgsUsage: "<id>...",
+            Action: locked(lock.Exclusive, rmCmd),
+         },
+         {
+            Name: "search",
+            Aliases: []string{"/"},
+            Usage: "full-text search notes",
+            ArgsUsage: "<term>...",
+            Action: locked(lock.Shared, searchCmd),
+            Flags: []cli.Flag{
+               &cli.IntFlag{
+                  Name: "limit",
+                  Aliases: []string{"n"},
+                  Usage: "show at most `N` results",
+                  Value: 10,
+               },
+               &cli.IntFlag{
+                  Name: "width",
+                  Aliases: []string{"w"},
+                  Usage: "width of the result snippets in `CHARS`",
+                  Value: 100,
+               },
+            },
+         },
+         {
+            Name: "show",
+            Aliases: []string{"s"},
+            Usage: "show a note",
+            ArgsUsage: "<id>",
+            Action: locked(lock.Shared, showCmd),
+            Flags: []cli.Flag{
+               &cli.BoolFlag{
+                  Name: "front-matter",
+                  Aliases: []string{"f"},
+                  Usage: "include the YAML front matter",
+               },
+            },
+         },
+         {
+            Name: "tag",
+            Usage: "curate tags",
+            Commands: append([]*cli.Command{
+               {
+                  Name: "rename",
+                  Aliases: []string{"mv"},
+                  Usage: "rename a tag, updating every note which has it",
+                  ArgsUsage: "<old> <new>",
+                  Action: locked(lock.Exclusive, tagRenameCmd),
+                  Flags: []cli.Flag{
+                     &cli.BoolFlag{
+                        Name: "keep-alias",
+                        Aliases: []string{"k"},
+                        Usage: "keep the old name as an alias of the tag",
+                     },
+                  },
+               },
+               {
+                  Name: "merge",
+                  Usage: "merge a tag into another, updating every note which has it",
+                  ArgsUsage: "<from> <into>",
+                  Action: locked(lock.Exclusive, tagMergeCmd),
+                  Flags: []cli.Flag{
+                     &cli.BoolFlag{
+                        Name: "keep-alias",
+                        Aliases: []string{"k"},
+                        Usage: "keep the merged tag's name as an alias",
+                     },
+                  },
+               },
+            }, tagEditCmds...),
+         },
+         {
+            Name: "template",
+            Usage: "manage note templates",
+            Commands: []*cli.Command{
+               {
+                  Name: "ls",
+                  Aliases: []string{"list"},
+                  Usage: "list the available note templates",
+                  Action: templateLsCmd,
+               },
+            },
+         },
+         {
+            Name: "trash",
+            Usage: "manage trashed notes",
+            Commands: []*cli.Command{
+               {
+                  Name: "list",
+                  Aliases: []string{"ls"},
+                  Usage: "list trashed notes",
+                  Action: locked(lock.Shared, trashListCmd),
+               },
+               {
+                  Name: "restore",
+                  Usage: "restore trashed notes",
+                  ArgsUsage: "<id>...",
+                  Action: locked(lock.Exclusive, trashRestoreCmd),
+               },
+               {
+                  Name: "empty",
+                  Usage: "permanently delete trashed notes",
+                  Action: locked(lock.Exclusive, trashEmptyCmd),
+                  Flags: []cli.Flag{
+                     &cli.DurationFlag{
+                        Name: "older-than",
+                        Usage: "only delete notes trashed more than `DURATION` ago",
+                     },
+                  },
+               },
+            },
          },
          {
             Name: "tui",
             Aliases: []string{"t"},
             Usage: "start the TUI",
+            Flags: []cli.Flag{
+               &cli.BoolFlag{
+                  Name: "untagged",
+                  Aliases: []string{"u"},
+                  Usage: "start in the view of notes without any tags, for triage",
+               },
+               &cli.BoolFlag{
+                  Name: "recent",
+                  Aliases: []string{"r"},
+                  Usage: "start in the view of the most recently edited notes",
+               },
+            },
             Action: tui.MainTui,
          },
       },
    }
 
-   cmd.Run(context.Background(), os.Args)
+   if err := cmd.Run(context.Background(), os.Args); err != nil {
+      fmt.Fprintln(os.Stderr, err)
+      os.Exit(1)
+   }
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+   "bytes"
+   "context"
+   "fmt"
+   "os"
+
+   "github.com/omnikron13/zelkata/config"
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/txn"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// migrateDatesCmd rewrites the dates in the front matter of every note in the given format, e.g. after changing
+// notes.metadata.date.format, so that the notes are all consistent. Either every note is rewritten, or none are. Notes
+// already in the format are left alone; the rest are saved as any other change would be, so are marked as modified and
+// get a new revision.
+func migrateDatesCmd(ctx context.Context, cmd *cli.Command) error {
+   to := cmd.String("to")
+   if err := note.CheckDateFormat(to); err != nil {
+      return err
+   }
+   files, err := note.Files()
+   if err != nil {
+      return err
+   }
+   tx := txn.New()
+   for _, f := range files {
+      old, err := os.ReadFile(f)
+      if err != nil {
+         return err
+      }
+      n, err := note.ReadFile(f)
+      if err != nil {
+         return err
+      }
+      if err := n.SetDateFormat(to); err != nil {
+         return err
+      }
+      b, err := n.Marshal()
+      if err != nil {
+         return err
+      }
+      if bytes.Equal(b, old) {
+         continue
+      }
+      if err := n.SaveTx(tx, f); err != nil {
+         return err
+      }
+   }
+   count := tx.Len()
+   if err := tx.Commit(); err != nil {
+      return err
+   }
+   fmt.Printf("rewrote the dates of %d note(s) as %s\n", count, to)
+
+   if configured := config.GetOrPanic[string]("notes.metadata.date.format"); note.Layout(configured) != note.Layout(to) {
+      fmt.Fprintf(os.Stderr, "warning: notes.metadata.date.format is still %s, so notes will be saved in that format from now on\n", configured)
+   }
+   return nil
+}
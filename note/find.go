@@ -0,0 +1,130 @@
+package note
+
+import (
+   "errors"
+   "fmt"
+   "os"
+   "path/filepath"
+   "slices"
+   "strings"
+
+   "github.com/omnikron13/zelkata/config"
+   "github.com/omnikron13/zelkata/paths"
+
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ErrNotFound is returned when no note matches an ID (or ID prefix).
+var ErrNotFound = errors.New("no note found with that ID")
+
+
+// AmbiguousIDError is returned when an ID prefix matches more than one note, much like git does with short hashes.
+type AmbiguousIDError struct {
+   // Prefix is the (partial) ID that was being looked up.
+   Prefix string
+
+   // Candidates are the full IDs of all of the notes which the prefix matches.
+   Candidates []string
+}
+
+
+func (e *AmbiguousIDError) Error() string {
+   return fmt.Sprintf("ambiguous note ID %q, candidates:\n  %s", e.Prefix, strings.Join(e.Candidates, "\n  "))
+}
+
+
+// FindByID resolves a full or partial (prefix) note ID to the path of the note file in the notes directory. If the
+// prefix matches more than one note an *AmbiguousIDError is returned listing all the candidates, though an exact match
+// always wins over any longer IDs sharing it as a prefix.
+func FindByID(id string) (string, error) {
+   return findByIDIn(paths.Notes(), id)
+}
+
+
+// findByIDIn does the actual work of FindByID, for an arbitrary directory.
+func findByIDIn(dir, id string) (path string, err error) {
+   if id == "" {
+      return "", ErrNotFound
+   }
+   entries, err := os.ReadDir(dir)
+   if err != nil {
+      return
+   }
+   var candidates []string
+   for _, e := range entries {
+      if !e.Type().IsRegular() {
+         continue
+      }
+      fileID := IDFromFileName(e.Name())
+      if fileID == id {
+         return filepath.Join(dir, e.Name()), nil
+      }
+      if fileID != "" && strings.HasPrefix(fileID, id) {
+         candidates = append(candidates, fileID)
+         path = filepath.Join(dir, e.Name())
+      }
+   }
+   switch len(candidates) {
+      case 0:
+         return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+      case 1:
+         return path, nil
+      default:
+         return "", &AmbiguousIDError{Prefix: id, Candidates: candidates}
+   }
+}
+
+
+// ResolveID resolves a full or partial (prefix) note ID against a set of known full note IDs, by the same rules as
+// FindByID; an exact match wins, otherwise the prefix has to match exactly one ID.
+func ResolveID(ids sets.Set[string], id string) (string, error) {
+   if id == "" {
+      return "", ErrNotFound
+   }
+   if ids.Has(id) {
+      return id, nil
+   }
+   var candidates []string
+   for full := range ids {
+      if strings.HasPrefix(full, id) {
+         candidates = append(candidates, full)
+      }
+   }
+   switch len(candidates) {
+      case 0:
+         return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+      case 1:
+         return candidates[0], nil
+      default:
+         slices.Sort(candidates)
+         return "", &AmbiguousIDError{Prefix: id, Candidates: candidates}
+   }
+}
+
+
+// IDs returns the IDs of all the notes in the notes directory, as given by their filenames.
+func IDs() (sets.Set[string], error) {
+   files, err := Files()
+   if err != nil {
+      return nil, err
+   }
+   ids := sets.New[string]()
+   for _, f := range files {
+      ids.Insert(IDFromFileName(f))
+   }
+   return ids, nil
+}
+
+
+// IDFromFileName extracts the note ID from a filename generated by GenFileName, or returns an empty string if the
+// name doesn't have the configured note extension.
+func IDFromFileName(name string) string {
+   ext := "." + config.GetOrPanic[string]("notes.filenames.suffix.extension")
+   name = filepath.Base(name)
+   if !strings.HasSuffix(name, ext) {
+      return ""
+   }
+   name = strings.TrimSuffix(name, ext)
+   // The date & time prefixes are dot-separated, and the ID encodings used never contain dots themselves
+   return name[strings.LastIndex(name, ".")+1:]
+}
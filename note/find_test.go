@@ -0,0 +1,63 @@
+package note
+
+import (
+   "errors"
+   "os"
+   "path/filepath"
+   "testing"
+
+   "github.com/stretchr/testify/assert"
+)
+
+
+func Test_findByIDIn(t *testing.T) {
+   dir := t.TempDir()
+   for _, f := range []string{
+      "2024-05-13.01-02.ABCDEF.md",
+      "2024-05-13.01-03.ABCXYZ.md",
+      "2024-05-14.09-30.ABC.md",
+      "2024-05-14.09-31.QWERTY.md",
+      "not-a-note.txt",
+   } {
+      if err := os.WriteFile(filepath.Join(dir, f), nil, 0600); err != nil {
+         t.Fatalf("Failed to create test file: %s", err)
+      }
+   }
+
+   t.Run("full ID", func(t *testing.T) {
+      path, err := findByIDIn(dir, "ABCDEF")
+      assert.Nil(t, err)
+      assert.Equal(t, filepath.Join(dir, "2024-05-13.01-02.ABCDEF.md"), path)
+   })
+
+   t.Run("unique prefix", func(t *testing.T) {
+      path, err := findByIDIn(dir, "QW")
+      assert.Nil(t, err)
+      assert.Equal(t, filepath.Join(dir, "2024-05-14.09-31.QWERTY.md"), path)
+   })
+
+   t.Run("exact match beats prefix", func(t *testing.T) {
+      path, err := findByIDIn(dir, "ABC")
+      assert.Nil(t, err)
+      assert.Equal(t, filepath.Join(dir, "2024-05-14.09-30.ABC.md"), path)
+   })
+
+   t.Run("ambiguous prefix", func(t *testing.T) {
+      _, err := findByIDIn(dir, "AB")
+      var ambiguous *AmbiguousIDError
+      assert.True(t, errors.As(err, &ambiguous))
+      assert.ElementsMatch(t, []string{"ABCDEF", "ABCXYZ", "ABC"}, ambiguous.Candidates)
+   })
+
+   t.Run("not found", func(t *testing.T) {
+      _, err := findByIDIn(dir, "ZZZ")
+      assert.True(t, errors.Is(err, ErrNotFound))
+   })
+}
+
+
+func Test_IDFromFileName(t *testing.T) {
+   assert.Equal(t, "0Q1W2E3R4T5Y6U7I8O9P", IDFromFileName("2024-05-13.01-02.0Q1W2E3R4T5Y6U7I8O9P.md"))
+   assert.Equal(t, "0Q1W2E3R4T5Y6U7I8O9P", IDFromFileName("/some/dir/0Q1W2E3R4T5Y6U7I8O9P.md"))
+   assert.Equal(t, "", IDFromFileName("test.tag.yaml"))
+}
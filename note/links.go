@@ -0,0 +1,46 @@
+package note
+
+import (
+   "regexp"
+   "strings"
+)
+
+// linkRegex matches wikilink-style links to other notes, i.e. [[ID]] or [[ID|label]].
+var linkRegex = regexp.MustCompile(`\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]*))?\]\]`)
+
+
+// Link is a link from the body of one note to another note.
+type Link struct {
+   // ID is the ID of the note linked to, exactly as it was written.
+   ID string
+
+   // Label is the text to display for the link, if one was given.
+   Label string
+}
+
+
+// ParseLinks returns all the links in a note body, in the order they appear. Links to the same note more than once are
+// all returned.
+func ParseLinks(body string) (links []Link) {
+   for _, m := range linkRegex.FindAllStringSubmatch(body, -1) {
+      id := strings.TrimSpace(m[1])
+      if id == "" {
+         continue
+      }
+      links = append(links, Link{ID: id, Label: strings.TrimSpace(m[2])})
+   }
+   return
+}
+
+
+// Links returns the IDs of all the notes linked to from the note body, each only once, in order of first appearance.
+func (n *Note) Links() (ids []string) {
+   seen := map[string]bool{}
+   for _, l := range ParseLinks(n.Body) {
+      if !seen[l.ID] {
+         seen[l.ID] = true
+         ids = append(ids, l.ID)
+      }
+   }
+   return
+}
@@ -0,0 +1,21 @@
+package note
+
+import (
+   "testing"
+
+   "github.com/stretchr/testify/assert"
+)
+
+
+func Test_ParseLinks(t *testing.T) {
+   body := "See [[ABC123]] and [[DEF456|the other one]], or [[ ABC123 ]] again.\n" +
+      "Not links: [[]], [[|label]], [single] and [[split\nacross lines]].\n"
+   assert.Equal(t, []Link{
+      {ID: "ABC123"},
+      {ID: "DEF456", Label: "the other one"},
+      {ID: "ABC123"},
+   }, ParseLinks(body))
+
+   n := Note{Body: body}
+   assert.Equal(t, []string{"ABC123", "DEF456"}, n.Links())
+}
@@ -3,6 +3,7 @@ package note
 import (
    "encoding/base32"
    "encoding/base64"
+   "errors"
    "fmt"
    "strings"
    "time"
@@ -45,9 +46,9 @@ type Meta struct {
    // could also be some form of URN identifying a book,research paper, etc. They could even simply be path pointing
    // to a file or directory on the local file system which is related to the note.
    //
-   // This basic key:value map is very likely to just serve as a placeholder until a more robust and powerful system
-   // is implemented.
-   Refs *map[string]string
+   // Each ref is named by its key, and is written to the front matter as a plain string from which its kind is
+   // inferred; see Ref.
+   Refs map[string]Ref
 
    // Format can be used to store the format of the note, e.g. MarkDown, AsciiDoc, etc. if it can't be inferred from
    // the note itself, user configuration, or file extension hints.
@@ -57,6 +58,18 @@ type Meta struct {
    // The fact this is an option raises a point of caution in that duplication (or worse inconsistency) could arise.
    // Plain text notes (etc?) _might_ want an explicit title though?
    Title *string
+
+   // Modified is the date & time the note was last re-saved, if it ever has been. Each save is also recorded (with a
+   // hash of the content) in the note's revision log; see the history package.
+   Modified *time.Time
+
+   // Extra holds any keys in the front matter which Zelkata doesn't itself use, e.g. those added by other tools (Obsidian,
+   // static site generators, etc.) or by hand, so that they are written back out exactly as they were read rather than
+   // silently dropped. Like the rest of the front matter they are written in sorted order, so files don't churn.
+   Extra map[string]yaml.Node
+
+   // dateFormat overrides the configured date format the dates are written in, if set; see SetDateFormat.
+   dateFormat string
 }
 
 
@@ -68,6 +81,28 @@ func NewMeta() (m Meta) {
 }
 
 
+// Validate checks that the Meta holds everything a note is required to have, as opposed to merely being parseable;
+// mostly relevant when the front matter has been edited by hand.
+func (m *Meta) Validate() error {
+   if m.ID == "" {
+      return fmt.Errorf("missing note ID")
+   }
+   if m.Created.IsZero() {
+      return fmt.Errorf("missing created date")
+   }
+   return nil
+}
+
+
+// LastModified returns when the note was last modified, which is when it was created if it has never been re-saved.
+func (m *Meta) LastModified() time.Time {
+   if m.Modified != nil {
+      return *m.Modified
+   }
+   return m.Created
+}
+
+
 // encodeID encodes an ID as a string ad specified in the config.
 func encodeID(id []byte) string {
    format, err := config.Get[string]("notes.metadata.id.encode.format")
@@ -224,16 +259,28 @@ func (m *Meta) GenFileName() string {
 
 // MarshalYAML implements the yaml.Marshaler interface for the Meta struct.
 func (m *Meta) MarshalYAML() (any, error) {
-   data := map[string]any{
-      "id": m.ID,
-      "tags": sets.List(m.Tags),
+   data := map[string]any{}
+   // Any extra keys go first, so that they can never override the fields Zelkata itself uses
+   for k, v := range m.Extra {
+      if !metaKeys.Has(k) {
+         data[k] = &v
+      }
    }
-   if created, err := marshalTime(m.Created); err != nil {
+   data["id"] = m.ID
+   data["tags"] = sets.List(m.Tags)
+   if created, err := m.marshalTime(m.Created); err != nil {
       return nil, err
    } else {
       data["created"] = created
    }
-   if m.Refs != nil {
+   if m.Modified != nil {
+      if modified, err := m.marshalTime(*m.Modified); err != nil {
+         return nil, err
+      } else {
+         data["modified"] = modified
+      }
+   }
+   if len(m.Refs) > 0 {
       data["refs"] = m.Refs
    }
    if m.Format != nil {
@@ -246,97 +293,252 @@ func (m *Meta) MarshalYAML() (any, error) {
 }
 
 
-// marshalTime is a helper function to marshal a time.Time into a string according to the config.
+// SetDateFormat sets the format the dates are written in when the Meta is marshalled, instead of the configured one;
+// either the name of one of the layouts predefined in the time package, or a custom layout. Formats which can't be
+// read back exactly are refused, see CheckDateFormat.
+func (m *Meta) SetDateFormat(format string) error {
+   if err := CheckDateFormat(format); err != nil {
+      return err
+   }
+   m.dateFormat = format
+   return nil
+}
+
+
+// marshalTime marshals a time.Time into a string in the Meta's date format, if it has one set.
+func (m *Meta) marshalTime(t time.Time) (string, error) {
+   if m.dateFormat == "" {
+      return marshalTime(t)
+   }
+   return t.Format(Layout(m.dateFormat)), nil
+}
+
+
+// marshalTime is a helper function to marshal a time.Time into a string according to the config. A configured format
+// which can't be read back exactly (see CheckDateFormat) is an error, rather than quietly losing part of every date.
 func marshalTime(t time.Time) (string, error) {
    format, err := config.Get[string]("notes.metadata.date.format")
    if err != nil {
       return "", err
    }
+   if err := CheckDateFormat(format); err != nil {
+      return "", fmt.Errorf("invalid notes.metadata.date.format: %w", err)
+   }
+   return t.Format(Layout(format)), nil
+}
 
-   switch format {
-      case "Layout":
-         return t.Format(time.Layout), nil
-      case "ANSIC":
-         return t.Format(time.ANSIC), nil
-      case "UnixDate":
-         return t.Format(time.UnixDate), nil
-      case "RubyDate":
-         return t.Format(time.RubyDate), nil
-      case "RFC822":
-         return t.Format(time.RFC822), nil
-      case "RFC822Z":
-         return t.Format(time.RFC822Z), nil
-      case "RFC850":
-         return t.Format(time.RFC850), nil
-      case "RFC1123":
-         return t.Format(time.RFC1123), nil
-      case "RFC1123Z":
-         return t.Format(time.RFC1123Z), nil
-      case "RFC3339":
-         return t.Format(time.RFC3339), nil
-      case "RFC3339Nano":
-         return t.Format(time.RFC3339Nano), nil
-      case "Kitchen":
-         return t.Format(time.Kitchen), nil
-      case "Stamp":
-         return t.Format(time.Stamp), nil
-      case "StampMilli":
-         return t.Format(time.StampMilli), nil
-      case "StampMicro":
-         return t.Format(time.StampMicro), nil
-      case "StampNano":
-         return t.Format(time.StampNano), nil
-      case "DateTime":
-         return t.Format(time.DateTime), nil
-      case "DateOnly":
-         return t.Format(time.DateOnly), nil
-      case "TimeOnly":
-         return t.Format(time.TimeOnly), nil
-      default:
-         return t.Format(format), nil
+
+// unmarshalTime is the inverse of marshalTime, parsing a date string according to the config. Should that fail, each
+// of the predefined layouts is tried in turn, so notes written before the configured format was last changed can still
+// be read.
+func unmarshalTime(s string) (time.Time, error) {
+   layout, err := dateLayout()
+   if err != nil {
+      return time.Time{}, err
+   }
+   t, err := time.Parse(layout, s)
+   if err == nil {
+      return t, nil
    }
+   for _, l := range namedLayouts {
+      if t, fallbackErr := time.Parse(l.layout, s); fallbackErr == nil {
+         return t, nil
+      }
+   }
+   return time.Time{}, err
 }
 
 
-// UnmarshalYAML implements the yaml.Unmarshaler interface for the Meta struct.
-func (m *Meta) UnmarshalYAML(value *yaml.Node) (err error) {
-   data := map[string]any{}
+// CheckDateFormat returns an error if dates written in the given format (a layout name or a custom layout) can't be
+// read back exactly, to the second; e.g. Kitchen, which doesn't include the date at all, or a custom layout other than
+// the configured one, as there would be no way of knowing what layout to read it as.
+func CheckDateFormat(format string) error {
+   sample := time.Date(2024, time.November, 23, 21, 34, 56, 0, time.UTC)
+   if t, err := unmarshalTime(sample.Format(Layout(format))); err != nil || !t.Equal(sample) {
+      return fmt.Errorf("dates can't be written as %q and read back exactly; perhaps it loses part of the date, or is a custom layout that isn't set as notes.metadata.date.format", format)
+   }
+   return nil
+}
 
-   err = value.Decode(&data)
-   if err != nil { return }
 
-   m.ID = data["id"].(string)
-   if m.ID == "" {
-      m = nil
-      err = fmt.Errorf("Missing note ID.")
+// dateLayout returns the time layout string for the configured date format.
+func dateLayout() (string, error) {
+   format, err := config.Get[string]("notes.metadata.date.format")
+   if err != nil {
+      return "", err
+   }
+   return Layout(format), nil
+}
+
+
+// namedLayouts are the layouts predefined in the time package, by the names they can be configured as. They are in
+// order of preference when trying to parse a date in an unknown layout, those with full dates & times first.
+var namedLayouts = []struct{ name, layout string }{
+   {"RFC3339Nano", time.RFC3339Nano},
+   {"RFC3339", time.RFC3339},
+   {"DateTime", time.DateTime},
+   {"RFC1123Z", time.RFC1123Z},
+   {"RFC1123", time.RFC1123},
+   {"RFC850", time.RFC850},
+   {"RFC822Z", time.RFC822Z},
+   {"RFC822", time.RFC822},
+   {"RubyDate", time.RubyDate},
+   {"UnixDate", time.UnixDate},
+   {"ANSIC", time.ANSIC},
+   {"Layout", time.Layout},
+   {"DateOnly", time.DateOnly},
+   {"StampNano", time.StampNano},
+   {"StampMicro", time.StampMicro},
+   {"StampMilli", time.StampMilli},
+   {"Stamp", time.Stamp},
+   {"Kitchen", time.Kitchen},
+   {"TimeOnly", time.TimeOnly},
+}
+
+
+// Layout returns the time layout string for a date format, which can either be the name of one of the layouts
+// predefined in the time package, or a custom layout.
+func Layout(format string) string {
+   for _, l := range namedLayouts {
+      if l.name == format {
+         return l.layout
+      }
+   }
+   return format
+}
+
+
+// metaKeys are the front matter keys Zelkata uses itself, i.e. those of metaYAML; anything else goes in Meta.Extra.
+var metaKeys = sets.New("id", "created", "modified", "tags", "refs", "format", "title")
+
+
+// metaYAML is the shape of the front matter as written in a note file. It is decoded into first so that a value of the
+// wrong type is reported by yaml, along with its line, rather than blindly asserted; the dates & refs are kept as nodes
+// so that they can be parsed (and any problem with them likewise reported) separately.
+type metaYAML struct {
+   ID string `yaml:"id"`
+   Created yaml.Node `yaml:"created"`
+   Modified yaml.Node `yaml:"modified"`
+   Tags []string `yaml:"tags"`
+   Refs yaml.Node `yaml:"refs"`
+   Format *string `yaml:"format"`
+   Title *string `yaml:"title"`
+}
+
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for the Meta struct. Problems with the values are returned
+// as a ParseError with the line of the offending value.
+func (m *Meta) UnmarshalYAML(value *yaml.Node) (err error) {
+   var raw metaYAML
+   if err = value.Decode(&raw); err != nil {
       return
    }
 
-   m.Created = data["created"].(time.Time)
+   m.ID = raw.ID
+   if m.ID == "" {
+      return &ParseError{Line: value.Line, Err: errors.New("missing note ID")}
+   }
 
-   tags := data["tags"].([]any)
-   m.Tags = sets.New[string]()
-   for _, t := range tags {
-      m.Tags.Insert(t.(string))
+   if raw.Created.IsZero() {
+      return &ParseError{Line: value.Line, Err: errors.New("missing created date")}
+   }
+   if m.Created, err = decodeTimeNode(&raw.Created); err != nil {
+      return &ParseError{Line: raw.Created.Line, Err: fmt.Errorf("invalid created date: %w", err)}
    }
 
-   if r, ok := data["refs"]; ok {
-      refs := make(map[string]string, len(r.(map[string]any)))
-      m.Refs = &refs
-      for k, v := range r.(map[string]any) {
-         (*m.Refs)[k] = v.(string)
+   if !raw.Modified.IsZero() {
+      modified, err := decodeTimeNode(&raw.Modified)
+      if err != nil {
+         return &ParseError{Line: raw.Modified.Line, Err: fmt.Errorf("invalid modified date: %w", err)}
       }
+      m.Modified = &modified
    }
 
-   if format, ok := data["format"]; ok {
-      f := format.(string)
-      m.Format = &f
+   m.Tags = sets.New(raw.Tags...)
+
+   if !raw.Refs.IsZero() && raw.Refs.Tag != "!!null" {
+      if raw.Refs.Kind != yaml.MappingNode {
+         return &ParseError{Line: raw.Refs.Line, Err: errors.New("refs should be a mapping of names to refs")}
+      }
+      m.Refs = make(map[string]Ref, len(raw.Refs.Content) / 2)
+      for i := 0; i + 1 < len(raw.Refs.Content); i += 2 {
+         k, v := raw.Refs.Content[i].Value, raw.Refs.Content[i+1]
+         if v.Kind != yaml.ScalarNode {
+            return &ParseError{Line: v.Line, Err: fmt.Errorf("invalid ref %q: should be a string", k)}
+         }
+         if m.Refs[k], err = ParseRef(v.Value); err != nil {
+            return &ParseError{Line: v.Line, Err: fmt.Errorf("invalid ref %q: %w", k, err)}
+         }
+      }
    }
 
-   if title, ok := data["title"]; ok {
-      t := title.(string)
-      m.Title = &t
+   m.Format = raw.Format
+   m.Title = raw.Title
+
+   if value.Kind == yaml.MappingNode {
+      budget := maxExtraNodes
+      for i := 0; i + 1 < len(value.Content); i += 2 {
+         if k := value.Content[i].Value; !metaKeys.Has(k) {
+            if m.Extra == nil {
+               m.Extra = map[string]yaml.Node{}
+            }
+            resolved, err := resolveAliases(value.Content[i+1], &budget)
+            if err != nil {
+               return &ParseError{Line: value.Content[i].Line, Err: fmt.Errorf("front matter key %q: %w", k, err)}
+            }
+            m.Extra[k] = resolved
+         }
+      }
    }
    return nil
 }
 
+
+// maxExtraNodes is the most nodes the Extra keys may expand to once their aliases are resolved. yaml only guards
+// against aliases expanding exponentially when decoding into values, not into nodes, so without a limit a small file
+// with a few levels of nested aliases could take forever to read.
+const maxExtraNodes = 10000
+
+
+// resolveAliases returns a copy of a node with every alias within it replaced by a copy of what it refers to, and any
+// anchors dropped. The Extra nodes are written back out separately from each other, and from the keys Zelkata rewrites
+// itself, so an alias kept as is could end up referring to an anchor which is no longer there. Every node copied is
+// taken from the budget, and an error returned should it run out.
+func resolveAliases(node *yaml.Node, budget *int) (yaml.Node, error) {
+   if node.Kind == yaml.AliasNode && node.Alias != nil {
+      return resolveAliases(node.Alias, budget)
+   }
+   if *budget--; *budget < 0 {
+      return yaml.Node{}, errors.New("aliases expand to too large a document")
+   }
+   resolved := *node
+   resolved.Anchor = ""
+   if node.Content != nil {
+      resolved.Content = make([]*yaml.Node, len(node.Content))
+      for i, c := range node.Content {
+         r, err := resolveAliases(c, budget)
+         if err != nil {
+            return yaml.Node{}, err
+         }
+         resolved.Content[i] = &r
+      }
+   }
+   return resolved, nil
+}
+
+
+// decodeTimeNode decodes a date from a node of the front matter. yaml will only decode the date as a time.Time itself if
+// it happens to look like one of the formats it knows; otherwise (e.g. when marshalTime quoted it, or it was written in
+// a custom layout yaml takes for a number) it has to be parsed from the raw text.
+func decodeTimeNode(node *yaml.Node) (time.Time, error) {
+   if node.Kind != yaml.ScalarNode {
+      return time.Time{}, errors.New("should be a date")
+   }
+   if node.ShortTag() == "!!timestamp" {
+      var t time.Time
+      if err := node.Decode(&t); err == nil {
+         return t, nil
+      }
+   }
+   return unmarshalTime(node.Value)
+}
@@ -1,6 +1,9 @@
 package note
 
 import (
+   "errors"
+   "fmt"
+   "strings"
    "testing"
    "time"
 
@@ -39,9 +42,9 @@ func Test_MarshalYAML(t *testing.T) {
       meta := Meta {
          ID: "123456789",
          Tags: sets.New("Foo", "Bar"),
-         Refs: &map[string]string{
-            "Website": "https://example.com",
-            "Book": "ISBN 1234567890",
+         Refs: map[string]Ref{
+            "Website": {Kind: RefURL, Value: "https://example.com"},
+            "Book": {Kind: RefURN, Value: "ISBN 1234567890"},
          },
          Format: &format,
          Title: &title,
@@ -83,6 +86,33 @@ func Test_UnmarshalYAML(t *testing.T) {
       assert.Equal(t, expected, meta)
    })
 
+   t.Run("quoted date", func(t *testing.T) {
+      data := "created: \"2024-05-13 01:02:03\"\nid: \"123456789\"\ntags: []\n"
+      meta := Meta{}
+      err := yaml.Unmarshal([]byte(data), &meta)
+      assert.Nil(t, err)
+      expected, err := time.Parse(time.RFC3339, "2024-05-13T01:02:03Z")
+      if err != nil { t.Fatalf("Failed to parse time: %s", err) }
+      assert.Equal(t, expected, meta.Created)
+   })
+
+   t.Run("modified", func(t *testing.T) {
+      data := "created: \"2024-05-13 01:02:03\"\nid: \"123456789\"\nmodified: \"2024-05-14 04:05:06\"\ntags: []\n"
+      meta := Meta{}
+      err := yaml.Unmarshal([]byte(data), &meta)
+      assert.Nil(t, err)
+      expected, err := time.Parse(time.RFC3339, "2024-05-14T04:05:06Z")
+      if err != nil { t.Fatalf("Failed to parse time: %s", err) }
+      if assert.NotNil(t, meta.Modified) {
+         assert.Equal(t, expected, *meta.Modified)
+      }
+      assert.Equal(t, expected, meta.LastModified())
+
+      out, err := yaml.Marshal(&meta)
+      assert.Nil(t, err)
+      assert.Equal(t, data, string(out))
+   })
+
    t.Run("complex meta", func(t *testing.T) {
       data := "created: 2024-05-13T01:02:03Z\nformat: AsciiDoc\nid: \"123456789\"\nrefs:\n    Book: ISBN 1234567890\n    Website: https://example.com\ntags:\n    - Bar\n    - Foo\ntitle: Test Note\n"
       meta := Meta{}
@@ -93,9 +123,9 @@ func Test_UnmarshalYAML(t *testing.T) {
       expected := Meta{
          ID: "123456789",
          Tags: sets.New("Foo", "Bar"),
-         Refs: &map[string]string{
-            "Website": "https://example.com",
-            "Book": "ISBN 1234567890",
+         Refs: map[string]Ref{
+            "Website": {Kind: RefURL, Value: "https://example.com"},
+            "Book": {Kind: RefURN, Value: "ISBN 1234567890"},
          },
          Format: &format,
 
@@ -108,3 +138,114 @@ func Test_UnmarshalYAML(t *testing.T) {
    })
 }
 
+
+func Test_Validate(t *testing.T) {
+   now, err := time.Parse(time.DateTime, "2024-05-13 01:02:03")
+   if err != nil { t.Fatalf("Failed to parse time: %s", err) }
+
+   assert.Nil(t, (&Meta{ID: "123456789", Created: now}).Validate())
+   assert.NotNil(t, (&Meta{Created: now}).Validate())
+   assert.NotNil(t, (&Meta{ID: "123456789"}).Validate())
+}
+
+
+func Test_unmarshalTime(t *testing.T) {
+   expected, err := time.Parse(time.RFC3339, "2024-05-13T01:02:03Z")
+   if err != nil { t.Fatalf("Failed to parse time: %s", err) }
+   // The configured format first, then falling back through the rest
+   for _, s := range []string{"2024-05-13 01:02:03", "2024-05-13T01:02:03Z", "Mon, 13 May 2024 01:02:03 UTC", "Mon May 13 01:02:03 UTC 2024"} {
+      parsed, err := unmarshalTime(s)
+      if assert.Nil(t, err, s) {
+         assert.True(t, expected.Equal(parsed), s)
+      }
+   }
+   _, err = unmarshalTime("last tuesday")
+   assert.NotNil(t, err)
+}
+
+
+func Test_SetDateFormat(t *testing.T) {
+   created, err := time.Parse(time.RFC3339, "2024-05-13T01:02:03Z")
+   if err != nil { t.Fatalf("Failed to parse time: %s", err) }
+
+   for _, format := range []string{"RFC3339", "RFC1123Z", "RFC850", "UnixDate", "ANSIC", "2006-01-02 15:04:05"} {
+      t.Run(format, func(t *testing.T) {
+         meta := Meta{ID: "123456789", Created: created, Tags: sets.New[string]()}
+         if !assert.Nil(t, meta.SetDateFormat(format)) {
+            return
+         }
+         data, err := yaml.Marshal(&meta)
+         assert.Nil(t, err)
+         read := Meta{}
+         if assert.Nil(t, yaml.Unmarshal(data, &read), string(data)) {
+            assert.True(t, created.Equal(read.Created), string(data))
+         }
+      })
+   }
+
+   // Those which lose part of the date, and custom layouts which aren't the configured one so couldn't be read back
+   for _, format := range []string{"Kitchen", "DateOnly", "RFC822", "Stamp", "no verbs", "02/01/2006 15:04:05"} {
+      assert.NotNil(t, (&Meta{}).SetDateFormat(format), format)
+   }
+}
+
+
+func Test_Extra(t *testing.T) {
+   data := "aliases:\n    - Another Name\ncreated: \"2024-05-13 01:02:03\"\nid: \"123456789\"\npublish: true\nsource: {url: 'https://example.com', page: 12} # where it came from\nstatus: draft\ntags:\n    - Foo\n"
+   meta := Meta{}
+   if err := yaml.Unmarshal([]byte(data), &meta); err != nil {
+      t.Fatalf("Failed to unmarshal: %s", err)
+   }
+   assert.Len(t, meta.Extra, 4)
+   assert.Equal(t, "draft", meta.Extra["status"].Value)
+
+   out, err := yaml.Marshal(&meta)
+   assert.Nil(t, err)
+   assert.Equal(t, data, string(out))
+
+   t.Run("cannot override", func(t *testing.T) {
+      meta.Extra["id"] = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "IMPOSTER"}
+      out, err := yaml.Marshal(&meta)
+      assert.Nil(t, err)
+      assert.Contains(t, string(out), "id: \"123456789\"\n")
+      assert.NotContains(t, string(out), "IMPOSTER")
+   })
+
+   t.Run("aliases", func(t *testing.T) {
+      // The anchors may be on keys which are rewritten, or on other Extra keys, so the aliases can't be kept as aliases
+      data := "id: ABC\ncreated: 2024-05-13 01:02:03\ntags: &t [Foo, Bar]\nalso: *t\nsource: &s {url: 'https://example.com'}\nmirror: *s\n"
+      meta := Meta{}
+      if err := yaml.Unmarshal([]byte(data), &meta); err != nil {
+         t.Fatalf("Failed to unmarshal: %s", err)
+      }
+      out, err := yaml.Marshal(&meta)
+      if !assert.Nil(t, err) {
+         return
+      }
+      again := Meta{}
+      if assert.Nil(t, yaml.Unmarshal(out, &again), "failed to re-read:\n%s", out) {
+         var also []string
+         node := again.Extra["also"]
+         assert.Nil(t, node.Decode(&also))
+         assert.Equal(t, []string{"Foo", "Bar"}, also)
+         var mirror map[string]string
+         node = again.Extra["mirror"]
+         assert.Nil(t, node.Decode(&mirror))
+         assert.Equal(t, map[string]string{"url": "https://example.com"}, mirror)
+      }
+   })
+   t.Run("alias bomb", func(t *testing.T) {
+      // Each level doubles the size of the last, so this would expand to billions of nodes
+      var sb strings.Builder
+      sb.WriteString("id: ABC\ncreated: 2024-05-13 01:02:03\nbomb:\n  - &l0 [x, x]\n")
+      for i := 1; i <= 30; i++ {
+         fmt.Fprintf(&sb, "  - &l%d [*l%d, *l%d]\n", i, i-1, i-1)
+      }
+      meta := Meta{}
+      err := yaml.Unmarshal([]byte(sb.String()), &meta)
+      var pe *ParseError
+      if assert.True(t, errors.As(err, &pe), "expected a ParseError, got %v", err) {
+         assert.Equal(t, 3, pe.Line)
+      }
+   })
+}
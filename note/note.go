@@ -3,10 +3,15 @@ package note
 
 import (
    "bytes"
+   "errors"
    "path/filepath"
    "os"
+   "strings"
+   "time"
 
+   "github.com/omnikron13/zelkata/config"
    "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/txn"
 
    "gopkg.in/yaml.v3"
 )
@@ -45,53 +50,172 @@ func New(body string) Note {
 }
 
 
-// genFile generates a byte slice representing the on-disk representation of the note.
-func (n *Note) genFile() []byte {
+// Marshal generates a byte slice representing the on-disk representation of the note.
+func (n *Note) Marshal() ([]byte, error) {
    var b bytes.Buffer
    b.WriteString("---\n")
    yml, err := yaml.Marshal(&n.Meta)
    if err != nil {
-      // TODO: rework this to more idiomatic Go error handling
-      panic(err)
+      return nil, err
    }
    b.Write(yml)
    // NOTE: the double newline after the front matter is intentional, as it prevents `glow` from rendering the front
    // matter as part of a line 1 heading in the rendered markdown.
    b.WriteString("...\n\n")
    b.WriteString(n.Body)
-   return b.Bytes()
+   return b.Bytes(), nil
 }
 
 
-// ReadFile reads a note file from disk and returns a Note struct.
-func ReadFile(path string) (n Note, err error) {
-   b, err := os.ReadFile(path)
+// DisplayTitle returns the best available title for the note; the explicit Title from the front matter if there is
+// one, otherwise the first MarkDown heading in the body (either ATX `# Heading` or Setext underlined style). An empty
+// string is returned if neither can be found.
+func (n *Note) DisplayTitle() string {
+   if n.Title != nil && *n.Title != "" {
+      return *n.Title
+   }
+   lines := strings.Split(n.Body, "\n")
+   for i, l := range lines {
+      l = strings.TrimSpace(l)
+      if strings.HasPrefix(l, "#") {
+         h := strings.TrimLeft(l, "#")
+         // ATX headings need at least one space between the hashes and the text, and at most 6 levels
+         if len(l) - len(h) > 6 || (h != "" && h[0] != ' ' && h[0] != '\t') {
+            continue
+         }
+         if h = strings.TrimSpace(strings.TrimRight(h, "# \t")); h != "" {
+            return h
+         }
+         continue
+      }
+      if l == "" || i+1 >= len(lines) {
+         continue
+      }
+      if u := strings.TrimSpace(lines[i+1]); u != "" && (strings.Trim(u, "=") == "" || strings.Trim(u, "-") == "") {
+         return l
+      }
+   }
+   return ""
+}
+
+
+// Files returns the paths of all the note files in the configured notes directory, in lexical order (which, with the
+// default filename configuration, is also chronological).
+func Files() (files []string, err error) {
+   ext := "." + config.GetOrPanic[string]("notes.filenames.suffix.extension")
+   entries, err := os.ReadDir(paths.Notes())
    if err != nil {
       return
    }
-   return readBytes(b)
+   for _, e := range entries {
+      if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), ext) {
+         continue
+      }
+      files = append(files, filepath.Join(paths.Notes(), e.Name()))
+   }
+   return
 }
 
 
-// readBytes reads a byte slice representing the on-disk representation of the note into the Note struct.
-func readBytes(b []byte) (n Note, err error) {
-   metaEnd := bytes.Index(b, []byte("\n...\n\n"))
-   if err = yaml.Unmarshal(b[:metaEnd], &n.Meta); err != nil {
+// ReadAll reads every note file in the notes directory, in the order Files returns them.
+func ReadAll() ([]Note, error) {
+   files, err := Files()
+   if err != nil {
+      return nil, err
+   }
+   notes := make([]Note, 0, len(files))
+   for _, f := range files {
+      n, err := ReadFile(f)
+      if err != nil {
+         // Both ParseError and the os errors already say which file it was
+         return nil, err
+      }
+      notes = append(notes, n)
+   }
+   return notes, nil
+}
+
+
+// ReadFile reads a note file from disk and returns a Note struct.
+func ReadFile(path string) (n Note, err error) {
+   b, err := os.ReadFile(path)
+   if err != nil {
       return
    }
-   n.Body = string(b[metaEnd+6:])
+   n, err = readBytes(b)
+   var pe *ParseError
+   if errors.As(err, &pe) {
+      pe.Path = path
+   }
    return
 }
 
 
 // Save saves the note to the configured notes directory and filename.
 func (n *Note) Save() error {
-   return n.saveAs(filepath.Join(paths.Notes(), n.GenFileName()))
+   return n.SaveAs(filepath.Join(paths.Notes(), n.GenFileName()))
+}
+
+
+// SaveAs saves the note to an arbitrary file path, e.g. back to wherever it was originally read from.
+func (n *Note) SaveAs(path string) error {
+   tx := txn.New()
+   if err := n.SaveTx(tx, path); err != nil {
+      return err
+   }
+   return tx.Commit()
+}
+
+
+// SaveTx queues saving the note to an arbitrary file path as part of a Txn, so that it is saved together with other
+// files (e.g. its tags) or not at all. The save hooks are called once the Txn has been committed.
+func (n *Note) SaveTx(tx *txn.Txn, path string) error {
+   // Overwriting the note's file means it is being re-saved, rather than saved for the first time
+   if _, err := os.Stat(path); err == nil {
+      modified := time.Now().UTC()
+      n.Modified = &modified
+   }
+   b, err := n.Marshal()
+   if err != nil {
+      return err
+   }
+   tx.Write(path, b, 0600)
+   // The hooks are called once for every note saved by the Txn, so that e.g. renaming a tag on many notes loads and
+   // saves each index once rather than once per note
+   saved, _ := tx.Value(savedKey{}).(*[]*Note)
+   if saved == nil {
+      saved = &[]*Note{}
+      tx.SetValue(savedKey{}, saved)
+      tx.OnCommit(func() error {
+         for _, h := range saveHooks {
+            if err := h(*saved...); err != nil {
+               return err
+            }
+         }
+         return nil
+      })
+   }
+   *saved = append(*saved, n)
+   return nil
 }
 
 
-// saveAs saves the note to the given path.
-func (n *Note) saveAs(path string) error {
-   return os.WriteFile(path, n.genFile(), 0600)
+// savedKey is the key under which the notes saved by a Txn are kept in it.
+type savedKey struct{}
+
+
+// SaveHook is a function to be called whenever notes have been saved, so that anything derived from notes (e.g. the
+// search index) can be kept up to date incrementally rather than constantly being rebuilt from scratch. All the notes
+// saved together by a Txn are passed in a single call.
+type SaveHook func(notes ...*Note) error
+
+// saveHooks holds the registered SaveHooks, in the order they were registered.
+var saveHooks []SaveHook
+
+
+// RegisterSaveHook adds a SaveHook to be called after every successful Save/SaveAs. This lets other packages hang
+// their own work off saving notes without the note package needing to know they exist.
+func RegisterSaveHook(h SaveHook) {
+   saveHooks = append(saveHooks, h)
 }
 
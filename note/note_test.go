@@ -1,9 +1,14 @@
 package note
 
 import (
+   "path/filepath"
    "testing"
    "time"
 
+   "github.com/omnikron13/zelkata/internal/testenv"
+   "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/txn"
+
    "github.com/stretchr/testify/assert"
    "k8s.io/apimachinery/pkg/util/sets"
 )
@@ -21,3 +26,55 @@ func Test_ReadFile(t *testing.T) {
    assert.Equal(t, "A Test Note\n===========\n\nThis is a test note.\n\n\nDetails\n-------\n\nPrimarily this note file is to test loading and parsing of note files, ensuring both the YAML front matter and MarkDown\nbody are correctly read back into a Note object that can be manipulated and saved back to disk.\n\n", n.Body)
 }
 
+
+
+func Test_DisplayTitle(t *testing.T) {
+   t.Run("explicit title", func(t *testing.T) {
+      title := "Explicit"
+      n := Note{Meta: Meta{Title: &title}, Body: "# Heading\n"}
+      assert.Equal(t, "Explicit", n.DisplayTitle())
+   })
+
+   t.Run("atx heading", func(t *testing.T) {
+      n := Note{Body: "#not-a-heading\n\n## An ATX Heading ##\n\nBody text.\n"}
+      assert.Equal(t, "An ATX Heading", n.DisplayTitle())
+   })
+
+   t.Run("setext heading", func(t *testing.T) {
+      n, err := ReadFile("testdata/testnote.md")
+      if err != nil { t.Fatalf("Failed to read test note: %s", err) }
+      assert.Equal(t, "A Test Note", n.DisplayTitle())
+   })
+
+   t.Run("no heading", func(t *testing.T) {
+      n := Note{Body: "Just some text.\n"}
+      assert.Equal(t, "", n.DisplayTitle())
+   })
+}
+
+
+func Test_SaveTx_hooks(t *testing.T) {
+   testenv.Setup(t)
+   var calls [][]string
+   defer func(hooks []SaveHook) { saveHooks = hooks }(saveHooks)
+   saveHooks = []SaveHook{func(notes ...*Note) error {
+      var ids []string
+      for _, n := range notes {
+         ids = append(ids, n.ID)
+      }
+      calls = append(calls, ids)
+      return nil
+   }}
+
+   // Every note saved by a Txn is passed to a single call of each hook, once it is committed
+   a, b := New("A.\n"), New("B.\n")
+   tx := txn.New()
+   assert.Nil(t, a.SaveTx(tx, filepath.Join(paths.Notes(), a.GenFileName())))
+   assert.Nil(t, b.SaveTx(tx, filepath.Join(paths.Notes(), b.GenFileName())))
+   assert.Empty(t, calls)
+   assert.Nil(t, tx.Commit())
+   assert.Equal(t, [][]string{{a.ID, b.ID}}, calls)
+
+   assert.Nil(t, a.Save())
+   assert.Equal(t, [][]string{{a.ID, b.ID}, {a.ID}}, calls)
+}
@@ -0,0 +1,139 @@
+package note
+
+import (
+   "bytes"
+   "errors"
+   "fmt"
+   "regexp"
+   "strconv"
+
+   "gopkg.in/yaml.v3"
+)
+
+
+// ParseError is an error reading a note file, saying where in the file the problem is.
+type ParseError struct {
+   // Path is the path of the note file, if it was read from one.
+   Path string
+
+   // Line is the line in the file the problem was found at, counting from 1, or 0 if it isn't known.
+   Line int
+
+   // Err is the underlying problem.
+   Err error
+}
+
+
+// Error implements the error interface, in the familiar path:line: message form.
+func (e *ParseError) Error() string {
+   switch {
+      case e.Path != "" && e.Line > 0:
+         return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Err)
+      case e.Path != "":
+         return fmt.Sprintf("%s: %s", e.Path, e.Err)
+      case e.Line > 0:
+         return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+      default:
+         return e.Err.Error()
+   }
+}
+
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+   return e.Err
+}
+
+
+// bom is the UTF-8 byte order mark, which some editors insist on adding to the start of files.
+var bom = []byte("\xEF\xBB\xBF")
+
+
+// yamlLine matches the line number yaml puts at the start of its error messages.
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+
+// ErrNoFrontMatter is the error (wrapped in a ParseError) given for a file which doesn't start with front matter.
+var ErrNoFrontMatter = errors.New("missing front matter; the file should start with a --- line")
+
+
+// readBytes reads a byte slice representing the on-disk representation of the note into the Note struct. It is
+// forgiving of files which have been edited by hand or by other tools, as described by SplitFrontMatter.
+func readBytes(b []byte) (n Note, err error) {
+   meta, body, err := SplitFrontMatter(b)
+   if err != nil {
+      return n, err
+   }
+   if err = yaml.Unmarshal(meta, &n.Meta); err != nil {
+      // Line numbers within the front matter are one out from the file, as the opening --- isn't part of it
+      return n, metaError(err, 1)
+   }
+   // yaml doesn't call Meta.UnmarshalYAML at all for an empty document
+   if n.ID == "" {
+      return n, &ParseError{Line: 2, Err: errors.New("missing note ID")}
+   }
+   n.Body = string(body)
+   return n, nil
+}
+
+
+// SplitFrontMatter splits the contents of a file into its YAML front matter, without the delimiters, and the body
+// following it. A byte order mark, CRLF line endings, the front matter ended with `---` rather than `...`, and no blank
+// line after it are all accepted. Only the front matter has its line endings normalised; the body is left exactly as
+// it is in the file.
+func SplitFrontMatter(b []byte) (meta, body []byte, err error) {
+   b = bytes.TrimPrefix(b, bom)
+   lines := bytes.SplitAfter(b, []byte("\n"))
+   if !isDelimiter(lines[0], "---") {
+      return nil, nil, &ParseError{Line: 1, Err: ErrNoFrontMatter}
+   }
+   end := 0
+   for i := 1; i < len(lines); i++ {
+      if isDelimiter(lines[i], "---") || isDelimiter(lines[i], "...") {
+         end = i
+         break
+      }
+   }
+   if end == 0 {
+      err = errors.New("front matter is never closed by a --- or ... line")
+      return nil, nil, &ParseError{Line: len(lines), Err: err}
+   }
+   meta = bytes.ReplaceAll(bytes.Join(lines[1:end], nil), []byte("\r\n"), []byte("\n"))
+
+   // A single blank line after the front matter is part of the format rather than the body
+   rest := lines[end+1:]
+   if len(rest) > 0 && len(bytes.TrimSpace(rest[0])) == 0 {
+      rest = rest[1:]
+   }
+   return meta, bytes.Join(rest, nil), nil
+}
+
+
+// isDelimiter reports whether a line is the given front matter delimiter, ignoring trailing whitespace.
+func isDelimiter(line []byte, delim string) bool {
+   return string(bytes.TrimRight(line, " \t\r\n")) == delim
+}
+
+
+// metaError converts an error from unmarshalling the front matter into a ParseError, with the line number offset to
+// where the front matter starts in the file.
+func metaError(err error, offset int) error {
+   var pe *ParseError
+   if errors.As(err, &pe) {
+      if pe.Line > 0 {
+         pe.Line += offset
+      }
+      return pe
+   }
+   // yaml.TypeError can hold several errors, but reporting the first is enough to go and fix the file
+   msg := err.Error()
+   var te *yaml.TypeError
+   if errors.As(err, &te) && len(te.Errors) > 0 {
+      msg = te.Errors[0]
+   }
+   if m := yamlLine.FindStringSubmatch(msg); m != nil {
+      line, _ := strconv.Atoi(m[1])
+      return &ParseError{Line: line + offset, Err: errors.New(m[2])}
+   }
+   return &ParseError{Err: err}
+}
@@ -0,0 +1,116 @@
+package note
+
+import (
+   "errors"
+   "os"
+   "strings"
+   "testing"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_readBytes(t *testing.T) {
+   t.Run("variations", func(t *testing.T) {
+      for name, data := range map[string]string{
+         "canonical": "---\nid: ABC\ncreated: 2024-05-13 01:02:03\ntags:\n  - Foo\n...\n\nBody.\n",
+         "dashes": "---\nid: ABC\ncreated: 2024-05-13 01:02:03\ntags:\n  - Foo\n---\n\nBody.\n",
+         "no blank line": "---\nid: ABC\ncreated: 2024-05-13 01:02:03\ntags:\n  - Foo\n...\nBody.\n",
+         "crlf": "---\r\nid: ABC\r\ncreated: 2024-05-13 01:02:03\r\ntags:\r\n  - Foo\r\n...\r\n\r\nBody.\r\n",
+         "bom": "\xEF\xBB\xBF---\nid: ABC\ncreated: 2024-05-13 01:02:03\ntags:\n  - Foo\n...  \n\nBody.\n",
+      } {
+         t.Run(name, func(t *testing.T) {
+            n, err := readBytes([]byte(data))
+            if assert.Nil(t, err) {
+               assert.Equal(t, "ABC", n.ID)
+               assert.Equal(t, sets.New("Foo"), n.Tags)
+               assert.Equal(t, "Body.\n", strings.ReplaceAll(n.Body, "\r\n", "\n"))
+            }
+         })
+      }
+   })
+
+   t.Run("crlf body", func(t *testing.T) {
+      // Only the front matter is normalised; the body is kept byte for byte, line endings and all
+      n, err := readBytes([]byte("---\r\nid: ABC\r\ncreated: 2024-05-13 01:02:03\r\n...\r\n\r\nOne.\r\nTwo.\n\r\n"))
+      if assert.Nil(t, err) {
+         assert.Equal(t, "One.\r\nTwo.\n\r\n", n.Body)
+      }
+   })
+
+   t.Run("no tags", func(t *testing.T) {
+      n, err := readBytes([]byte("---\nid: ABC\ncreated: 2024-05-13 01:02:03\n...\n"))
+      assert.Nil(t, err)
+      assert.Empty(t, n.Tags)
+      assert.Equal(t, "", n.Body)
+   })
+
+   t.Run("errors", func(t *testing.T) {
+      for name, c := range map[string]struct{ data string; line int }{
+         "empty": {"", 1},
+         "no front matter": {"Just a body.\n", 1},
+         "unterminated": {"---\nid: ABC\ncreated: 2024-05-13 01:02:03\n", 4},
+         "syntax": {"---\nid: ABC\ncreated: [\n...\n", 3},
+         "empty front matter": {"---\n...\n", 2},
+         "missing id": {"---\ncreated: 2024-05-13 01:02:03\n...\n", 2},
+         "bad date": {"---\nid: ABC\ncreated: last tuesday\n...\n", 3},
+         "bad tags": {"---\nid: ABC\ncreated: 2024-05-13 01:02:03\ntags:\n  nested: map\n...\n", 5},
+         "bad ref": {"---\nid: ABC\ncreated: 2024-05-13 01:02:03\nrefs:\n  Book: [isbn]\n...\n", 5},
+      } {
+         t.Run(name, func(t *testing.T) {
+            _, err := readBytes([]byte(c.data))
+            var pe *ParseError
+            if assert.True(t, errors.As(err, &pe), "expected a ParseError, got %v", err) {
+               assert.Equal(t, c.line, pe.Line)
+            }
+         })
+      }
+   })
+}
+
+
+func Test_ReadFile_ParseError(t *testing.T) {
+   path := t.TempDir() + "/bad.md"
+   if err := os.WriteFile(path, []byte("---\nid: ABC\ncreated: last tuesday\n...\n"), 0600); err != nil {
+      t.Fatalf("Failed to write note: %s", err)
+   }
+   _, err := ReadFile(path)
+   var pe *ParseError
+   if assert.True(t, errors.As(err, &pe)) {
+      assert.Equal(t, path, pe.Path)
+      assert.Contains(t, err.Error(), path + ":3: invalid created date")
+   }
+}
+
+
+func FuzzReadBytes(f *testing.F) {
+   f.Add([]byte("---\nid: ABC\ncreated: 2024-05-13 01:02:03\ntags:\n  - Foo\n...\n\nBody.\n"))
+   f.Add([]byte("---\r\nid: ABC\r\ncreated: \"2024-05-13 01:02:03\"\r\ntags: []\r\n---\r\nBody.\r\n"))
+   f.Add([]byte("\xEF\xBB\xBF---\nid: ABC\ncreated: 2024-05-13T01:02:03Z\nrefs:\n  Web: https://example.com\n...\n"))
+   f.Add([]byte("---\n...\n"))
+   f.Add([]byte("---\n"))
+   f.Add([]byte(""))
+   f.Fuzz(func(t *testing.T, data []byte) {
+      n, err := readBytes(data)
+      if err != nil {
+         var pe *ParseError
+         if !errors.As(err, &pe) {
+            t.Fatalf("error is not a ParseError: %v", err)
+         }
+         return
+      }
+      // Anything which can be read should survive being written back out and read again
+      b, err := n.Marshal()
+      if err != nil {
+         return
+      }
+      again, err := readBytes(b)
+      if err != nil {
+         t.Fatalf("failed to re-read marshalled note: %v\n%s", err, b)
+      }
+      if again.ID != n.ID || again.Body != n.Body || !again.Tags.Equal(n.Tags) {
+         t.Fatalf("note changed on round trip:\n%q\n%q", data, b)
+      }
+   })
+}
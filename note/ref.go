@@ -0,0 +1,226 @@
+package note
+
+import (
+   "fmt"
+   "net/url"
+   "os"
+   "path/filepath"
+   "regexp"
+   "strings"
+
+   "github.com/omnikron13/zelkata/paths"
+
+   "gopkg.in/yaml.v3"
+)
+
+// RefKind identifies what sort of thing a Ref points to.
+type RefKind string
+
+const (
+   // RefURL is a URL, e.g. a web page related to the note.
+   RefURL RefKind = "url"
+
+   // RefFile is a path to a file or directory on the local file system.
+   RefFile RefKind = "file"
+
+   // RefURN is a URN identifying a book or paper; currently either an ISBN or a DOI.
+   RefURN RefKind = "urn"
+
+   // RefNote is the ID of another note.
+   RefNote RefKind = "note"
+
+   // RefTag is the name of a tag.
+   RefTag RefKind = "tag"
+)
+
+var (
+   // isbnRegex matches the various ways an ISBN tends to be written, e.g. "ISBN 0306406152", "ISBN-13: 978-0-306"...
+   isbnRegex = regexp.MustCompile(`(?i)^(?:urn:)?isbn(?:-1[03])?(?::\s*|\s+)([0-9Xx][0-9Xx \-]*)$`)
+
+   // doiRegex matches a DOI, with or without a doi: prefix.
+   doiRegex = regexp.MustCompile(`(?i)^(?:(?:urn:)?doi:\s*)?(10\.[0-9]{4,9}/\S+)$`)
+)
+
+
+// Ref is a single reference from a note to something outside of its body. In the front matter refs are written as
+// plain strings, as they always have been, with the kind inferred from the form of the string:
+//
+//    https://example.com   a URL; anything with a scheme://
+//    ISBN 0306406152       an ISBN URN
+//    doi:10.1000/182       a DOI URN
+//    note:<id>             another note
+//    tag:<name>            a tag
+//    ~/papers/foo.pdf      a file; anything else, optionally prefixed with file:
+type Ref struct {
+   // Kind is the kind of thing the ref points to.
+   Kind RefKind
+
+   // Value is the URL, path, note ID or tag name pointed to. URNs are kept just as they were written, e.g.
+   // "ISBN 0-306-40615-2", and only normalised (see URN) to validate and compare them.
+   Value string
+}
+
+
+// ParseRef infers the kind of a ref from its string form. It does not validate the ref beyond that, as refs which were
+// written by hand shouldn't stop a note being read; see Validate.
+func ParseRef(s string) (Ref, error) {
+   s = strings.TrimSpace(s)
+   switch {
+      case s == "":
+         return Ref{}, fmt.Errorf("empty ref")
+      case strings.HasPrefix(s, "note:"):
+         return Ref{Kind: RefNote, Value: strings.TrimSpace(strings.TrimPrefix(s, "note:"))}, nil
+      case strings.HasPrefix(s, "tag:"):
+         return Ref{Kind: RefTag, Value: strings.TrimSpace(strings.TrimPrefix(s, "tag:"))}, nil
+      case strings.HasPrefix(s, "file://"):
+         return Ref{Kind: RefFile, Value: strings.TrimPrefix(s, "file://")}, nil
+      case strings.HasPrefix(s, "file:"):
+         return Ref{Kind: RefFile, Value: strings.TrimPrefix(s, "file:")}, nil
+      case strings.Contains(s, "://"):
+         return Ref{Kind: RefURL, Value: s}, nil
+   }
+   if isbnRegex.MatchString(s) || doiRegex.MatchString(s) {
+      return Ref{Kind: RefURN, Value: s}, nil
+   }
+   return Ref{Kind: RefFile, Value: s}, nil
+}
+
+
+// URN returns the normalised form of a URN ref; its namespace and identifier, without any separators in an ISBN, e.g.
+// "isbn:0306406152" for "ISBN 0-306-40615-2". Returns "" if the ref isn't a URN, or isn't an ISBN or DOI.
+func (r Ref) URN() string {
+   if r.Kind != RefURN {
+      return ""
+   }
+   if m := isbnRegex.FindStringSubmatch(r.Value); m != nil {
+      return "isbn:" + strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(m[1]))
+   }
+   if m := doiRegex.FindStringSubmatch(r.Value); m != nil {
+      return "doi:" + m[1]
+   }
+   return ""
+}
+
+
+// Equal reports whether two refs point to the same thing; URNs are compared in their normalised form, so that e.g.
+// "ISBN 0-306-40615-2" and "isbn:0306406152" are equal.
+func (r Ref) Equal(o Ref) bool {
+   if r.Kind == RefURN && o.Kind == RefURN && r.URN() != "" {
+      return r.URN() == o.URN()
+   }
+   return r == o
+}
+
+
+// String returns the string form of the ref, as written to the front matter, which ParseRef parses back to the same
+// Ref.
+func (r Ref) String() string {
+   switch r.Kind {
+      case RefNote, RefTag:
+         return string(r.Kind) + ":" + r.Value
+      case RefFile:
+         // A path which would otherwise be mistaken for another kind needs the prefix to disambiguate it
+         if ref, err := ParseRef(r.Value); err != nil || ref != r {
+            return "file:" + r.Value
+         }
+   }
+   return r.Value
+}
+
+
+// Validate checks that the ref is well formed for its kind, e.g. that an ISBN has a valid check digit. It does not
+// check that whatever the ref points to exists.
+func (r Ref) Validate() error {
+   if r.Value == "" {
+      return fmt.Errorf("empty %s ref", r.Kind)
+   }
+   switch r.Kind {
+      case RefURL:
+         u, err := url.Parse(r.Value)
+         if err != nil {
+            return err
+         }
+         if u.Scheme == "" || u.Host == "" {
+            return fmt.Errorf("invalid URL: %s", r.Value)
+         }
+      case RefURN:
+         urn := r.URN()
+         if isbn, ok := strings.CutPrefix(urn, "isbn:"); ok {
+            if !validISBN(isbn) {
+               return fmt.Errorf("invalid ISBN: %s", r.Value)
+            }
+         } else if urn == "" {
+            return fmt.Errorf("invalid URN: %s", r.Value)
+         }
+      case RefNote:
+         if strings.ContainsAny(r.Value, " \t\n") {
+            return fmt.Errorf("invalid note ID: %q", r.Value)
+         }
+      case RefFile, RefTag:
+      default:
+         return fmt.Errorf("unknown ref kind: %s", r.Kind)
+   }
+   return nil
+}
+
+
+// Path returns the local file system path of a file ref, with a leading ~ expanded to the home directory. Relative
+// paths are taken to be relative to the notes directory, as that is where the note referring to them lives.
+func (r Ref) Path() string {
+   p := r.Value
+   if rest, ok := strings.CutPrefix(p, "~/"); ok {
+      if home, err := os.UserHomeDir(); err == nil {
+         p = filepath.Join(home, rest)
+      }
+   }
+   if !filepath.IsAbs(p) {
+      p = filepath.Join(paths.Notes(), p)
+   }
+   return p
+}
+
+
+// validISBN reports whether an ISBN-10 or ISBN-13 (without separators) has a valid check digit.
+func validISBN(isbn string) bool {
+   switch len(isbn) {
+      case 10:
+         sum := 0
+         for i, c := range isbn {
+            d := int(c - '0')
+            if c == 'X' && i == 9 {
+               d = 10
+            } else if c < '0' || c > '9' {
+               return false
+            }
+            sum += d * (10 - i)
+         }
+         return sum % 11 == 0
+      case 13:
+         sum := 0
+         for i, c := range isbn {
+            if c < '0' || c > '9' {
+               return false
+            }
+            sum += int(c - '0') * (1 + 2 * (i % 2))
+         }
+         return sum % 10 == 0
+   }
+   return false
+}
+
+
+// MarshalYAML implements the yaml.Marshaler interface, writing the ref as its string form.
+func (r Ref) MarshalYAML() (any, error) {
+   return r.String(), nil
+}
+
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface, parsing the ref from its string form.
+func (r *Ref) UnmarshalYAML(value *yaml.Node) (err error) {
+   var s string
+   if err = value.Decode(&s); err != nil {
+      return
+   }
+   *r, err = ParseRef(s)
+   return
+}
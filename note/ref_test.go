@@ -0,0 +1,80 @@
+package note
+
+import (
+   "testing"
+
+   "github.com/stretchr/testify/assert"
+   "gopkg.in/yaml.v3"
+)
+
+
+func Test_ParseRef(t *testing.T) {
+   tests := []struct {
+      in string
+      want Ref
+      str string
+      valid bool
+   }{
+      {"https://example.com/foo", Ref{RefURL, "https://example.com/foo"}, "https://example.com/foo", true},
+      {"https://", Ref{RefURL, "https://"}, "https://", false},
+      {"ISBN 0-306-40615-2", Ref{RefURN, "ISBN 0-306-40615-2"}, "ISBN 0-306-40615-2", true},
+      {"isbn-13: 978-0-306-40615-7", Ref{RefURN, "isbn-13: 978-0-306-40615-7"}, "isbn-13: 978-0-306-40615-7", true},
+      {"ISBN 1234567890", Ref{RefURN, "ISBN 1234567890"}, "ISBN 1234567890", false},
+      {"doi:10.1000/182", Ref{RefURN, "doi:10.1000/182"}, "doi:10.1000/182", true},
+      {"10.1000/182", Ref{RefURN, "10.1000/182"}, "10.1000/182", true},
+      {"note:0Q1W2E3R4T", Ref{RefNote, "0Q1W2E3R4T"}, "note:0Q1W2E3R4T", true},
+      {"note:not an id", Ref{RefNote, "not an id"}, "note:not an id", false},
+      {"tag:Music", Ref{RefTag, "Music"}, "tag:Music", true},
+      {"~/papers/foo.pdf", Ref{RefFile, "~/papers/foo.pdf"}, "~/papers/foo.pdf", true},
+      {"file:///tmp/foo", Ref{RefFile, "/tmp/foo"}, "/tmp/foo", true},
+      {"file:note:odd", Ref{RefFile, "note:odd"}, "file:note:odd", true},
+   }
+   for _, tt := range tests {
+      t.Run(tt.in, func(t *testing.T) {
+         r, err := ParseRef(tt.in)
+         assert.Nil(t, err)
+         assert.Equal(t, tt.want, r)
+         assert.Equal(t, tt.str, r.String())
+         assert.Equal(t, tt.valid, r.Validate() == nil, "Validate() = %v", r.Validate())
+         // The string form must always parse back to the same ref
+         again, err := ParseRef(r.String())
+         assert.Nil(t, err)
+         assert.Equal(t, r, again)
+      })
+   }
+
+   _, err := ParseRef("  ")
+   assert.NotNil(t, err)
+}
+
+
+func Test_Ref_URN(t *testing.T) {
+   a, _ := ParseRef("ISBN 0-306-40615-2")
+   b, _ := ParseRef("urn:isbn:0306406152")
+   c, _ := ParseRef("ISBN 9780306406157")
+   assert.Equal(t, "isbn:0306406152", a.URN())
+   assert.True(t, a.Equal(b))
+   assert.False(t, a.Equal(c))
+
+   d, _ := ParseRef("10.1000/182")
+   e, _ := ParseRef("DOI: 10.1000/182")
+   assert.Equal(t, "doi:10.1000/182", d.URN())
+   assert.True(t, d.Equal(e))
+   assert.False(t, a.Equal(d))
+
+   // Other kinds of ref have no URN, and are compared as they are
+   f, _ := ParseRef("tag:Music")
+   assert.Equal(t, "", f.URN())
+   assert.True(t, f.Equal(Ref{RefTag, "Music"}))
+   assert.False(t, f.Equal(Ref{RefTag, "music"}))
+}
+
+
+func Test_Ref_YAML(t *testing.T) {
+   refs := map[string]Ref{}
+   assert.Nil(t, yaml.Unmarshal([]byte("a: note:ABC\nb: ISBN 0-306-40615-2\n"), &refs))
+   assert.Equal(t, map[string]Ref{"a": {RefNote, "ABC"}, "b": {RefURN, "ISBN 0-306-40615-2"}}, refs)
+   data, err := yaml.Marshal(refs)
+   assert.Nil(t, err)
+   assert.Equal(t, "a: note:ABC\nb: ISBN 0-306-40615-2\n", string(data))
+}
@@ -15,6 +15,11 @@ var dataDir string
 var noteDir string
 var tagDir  string
 var stateDir string
+var trashDir string
+var templateDir string
+var draftDir string
+var revisionDir string
+var journalDir string
 
 
 // Data returns the path to the root data directory that Zelkata is to use.
@@ -73,6 +78,45 @@ func Tags() string {
 }
 
 
+// Trash returns the path to the trash directory, where removed notes are kept until the trash is emptied.
+func Trash() string {
+   if trashDir != "" {
+      return trashDir
+   }
+   trashDir = filepath.Join(Data(), "trash")
+   if err := os.MkdirAll(trashDir, 0700); err != nil {
+      panic(err)
+   }
+   return trashDir
+}
+
+
+// Revisions returns the path to the directory of note revision logs.
+func Revisions() string {
+   if revisionDir != "" {
+      return revisionDir
+   }
+   revisionDir = filepath.Join(Data(), "revisions")
+   if err := os.MkdirAll(revisionDir, 0700); err != nil {
+      panic(err)
+   }
+   return revisionDir
+}
+
+
+// Templates returns the path to the directory of user note templates.
+func Templates() string {
+   if templateDir != "" {
+      return templateDir
+   }
+   templateDir = filepath.Join(Data(), "templates")
+   if err := os.MkdirAll(templateDir, 0700); err != nil {
+      panic(err)
+   }
+   return templateDir
+}
+
+
 // State returns the path to the state directory.
 func State() string {
    if stateDir != "" {
@@ -85,3 +129,38 @@ func State() string {
    return stateDir
 }
 
+
+
+// Drafts returns the path to the directory holding drafts of notes which are still being added.
+func Drafts() string {
+   if draftDir != "" {
+      return draftDir
+   }
+   draftDir = filepath.Join(State(), "drafts")
+   if err := os.MkdirAll(draftDir, 0700); err != nil {
+      panic(err)
+   }
+   return draftDir
+}
+
+
+// Journal returns the path to the directory holding the journals of multi-file changes which are being made, so that
+// they can be recovered if interrupted.
+func Journal() string {
+   if journalDir != "" {
+      return journalDir
+   }
+   journalDir = filepath.Join(State(), "journal")
+   if err := os.MkdirAll(journalDir, 0700); err != nil {
+      panic(err)
+   }
+   return journalDir
+}
+
+
+// Reset forgets all of the cached paths, so that they are worked out afresh from the config and environment the next
+// time they are asked for; e.g. by tests which point the directories somewhere temporary.
+func Reset() {
+   dataDir, noteDir, tagDir, stateDir, trashDir = "", "", "", "", ""
+   templateDir, draftDir, revisionDir, journalDir = "", "", "", ""
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "slices"
+   "strings"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/query"
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// queryCmd lists the notes matching a boolean tag query, e.g. `music AND (guitar OR banjo) AND NOT draft`, in the
+// same format as listCmd.
+func queryCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() < 1 {
+      return errors.New("query requires a query expression")
+   }
+   expr, err := query.Parse(strings.Join(cmd.Args().Slice(), " "))
+   if err != nil {
+      return err
+   }
+   tm, err := tags.LoadAll()
+   if err != nil {
+      return err
+   }
+   notes, err := note.ReadAll()
+   if err != nil {
+      return err
+   }
+
+   tm.ApplyVirtual(notes)
+
+   // The universe for NOT is every note, not just every tagged note
+   qctx := query.NewContext(tm)
+   qctx.Recursive = cmd.Bool("recursive")
+   for _, n := range notes {
+      qctx.All.Insert(n.ID)
+   }
+   matches := expr.Eval(qctx)
+
+   notes = slices.DeleteFunc(notes, func(n note.Note) bool { return !matches.Has(n.ID) })
+   slices.SortStableFunc(notes, func(a, b note.Note) int { return a.Created.Compare(b.Created) })
+   printNotes(tm, notes)
+   return nil
+}
+
@@ -0,0 +1,208 @@
+package query
+
+import (
+   "fmt"
+   "strings"
+)
+
+
+// SyntaxError is returned when a query can't be parsed, recording where in the query the problem was found.
+type SyntaxError struct {
+   // Pos is the byte offset into the query of the problem.
+   Pos int
+
+   // Msg describes the problem.
+   Msg string
+}
+
+
+func (e *SyntaxError) Error() string {
+   return fmt.Sprintf("query syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+
+// tokenKind identifies the kind of a lexical token.
+type tokenKind int
+
+const (
+   tokEOF tokenKind = iota
+   tokName
+   tokAnd
+   tokOr
+   tokNot
+   tokLParen
+   tokRParen
+)
+
+
+// token is a single lexical token from a query.
+type token struct {
+   kind tokenKind
+   text string
+   pos  int
+}
+
+
+// lex splits a query into tokens.
+func lex(q string) (toks []token, err error) {
+   i := 0
+   for i < len(q) {
+      r := rune(q[i])
+      switch {
+         case strings.ContainsRune(" \t\r\n", r):
+            i++
+         case r == '(':
+            toks = append(toks, token{tokLParen, "(", i})
+            i++
+         case r == ')':
+            toks = append(toks, token{tokRParen, ")", i})
+            i++
+         case r == '"':
+            end := strings.IndexByte(q[i+1:], '"')
+            if end < 0 {
+               return nil, &SyntaxError{i, "unterminated quoted tag name"}
+            }
+            toks = append(toks, token{tokName, q[i+1 : i+1+end], i})
+            i += end + 2
+         default:
+            start := i
+            for i < len(q) && !strings.ContainsRune(" \t\r\n()\"", rune(q[i])) {
+               i++
+            }
+            word := q[start:i]
+            switch keyword(word) {
+               case "AND":
+                  toks = append(toks, token{tokAnd, word, start})
+               case "OR":
+                  toks = append(toks, token{tokOr, word, start})
+               case "NOT":
+                  toks = append(toks, token{tokNot, word, start})
+               default:
+                  toks = append(toks, token{tokName, word, start})
+            }
+      }
+   }
+   return append(toks, token{tokEOF, "", len(q)}), nil
+}
+
+
+// keyword returns the canonical form of s if it's one of the operator keywords, or an empty string otherwise.
+func keyword(s string) string {
+   switch k := strings.ToUpper(s); k {
+      case "AND", "OR", "NOT":
+         return k
+   }
+   return ""
+}
+
+
+// parser is a simple recursive descent parser over the tokens of a query.
+type parser struct {
+   toks []token
+   i    int
+}
+
+
+// Parse parses a query into an expression tree.
+func Parse(q string) (Expr, error) {
+   toks, err := lex(q)
+   if err != nil {
+      return nil, err
+   }
+   p := &parser{toks: toks}
+   e, err := p.or()
+   if err != nil {
+      return nil, err
+   }
+   if t := p.peek(); t.kind != tokEOF {
+      return nil, &SyntaxError{t.pos, fmt.Sprintf("unexpected %q", t.text)}
+   }
+   return e, nil
+}
+
+
+func (p *parser) peek() token {
+   return p.toks[p.i]
+}
+
+
+func (p *parser) next() token {
+   t := p.toks[p.i]
+   if t.kind != tokEOF {
+      p.i++
+   }
+   return t
+}
+
+
+// or := and { OR and }
+func (p *parser) or() (Expr, error) {
+   l, err := p.and()
+   if err != nil {
+      return nil, err
+   }
+   for p.peek().kind == tokOr {
+      p.next()
+      r, err := p.and()
+      if err != nil {
+         return nil, err
+      }
+      l = Or{l, r}
+   }
+   return l, nil
+}
+
+
+// and := unary { AND unary }
+func (p *parser) and() (Expr, error) {
+   l, err := p.unary()
+   if err != nil {
+      return nil, err
+   }
+   for p.peek().kind == tokAnd {
+      p.next()
+      r, err := p.unary()
+      if err != nil {
+         return nil, err
+      }
+      l = And{l, r}
+   }
+   return l, nil
+}
+
+
+// unary := NOT unary | primary
+func (p *parser) unary() (Expr, error) {
+   if p.peek().kind == tokNot {
+      p.next()
+      x, err := p.unary()
+      if err != nil {
+         return nil, err
+      }
+      return Not{x}, nil
+   }
+   return p.primary()
+}
+
+
+// primary := "(" or ")" | name
+func (p *parser) primary() (Expr, error) {
+   t := p.next()
+   switch t.kind {
+      case tokName:
+         return Tag{t.text}, nil
+      case tokLParen:
+         e, err := p.or()
+         if err != nil {
+            return nil, err
+         }
+         if c := p.next(); c.kind != tokRParen {
+            return nil, &SyntaxError{c.pos, "expected closing parenthesis"}
+         }
+         return e, nil
+      case tokEOF:
+         return nil, &SyntaxError{t.pos, "unexpected end of query"}
+      default:
+         return nil, &SyntaxError{t.pos, fmt.Sprintf("unexpected %q", t.text)}
+   }
+}
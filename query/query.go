@@ -0,0 +1,107 @@
+// Package query implements a small boolean query language for selecting notes by their tags, e.g.
+//
+//    music AND (guitar OR banjo) AND NOT draft
+//
+// Tag names are resolved through a tags.TagMap, so aliases work just as they do anywhere else. Names containing
+// spaces, parentheses, or which clash with the operators, can be written in double quotes. Operators are case
+// insensitive, and bind in the usual order; NOT tightest, then AND, then OR.
+package query
+
+import (
+   "fmt"
+   "strings"
+
+   "github.com/omnikron13/zelkata/tags"
+
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+// Expr is a node in a parsed query.
+type Expr interface {
+   // Eval returns the IDs of the notes which match the expression.
+   Eval(ctx *Context) sets.Set[string]
+
+   // String returns a normalised, fully parenthesised, form of the expression.
+   String() string
+}
+
+
+// Context holds everything a query is evaluated against.
+type Context struct {
+   // Tags is the TagMap used to resolve tag names (and aliases) to the notes which have them.
+   Tags tags.TagMap
+
+   // All is the set of every note ID, which NOT needs as the universe to take the complement from.
+   All sets.Set[string]
+
+   // Recursive includes the notes of all of a tag's descendants (tags which have it as a parent, directly or
+   // indirectly) when evaluating it.
+   Recursive bool
+}
+
+
+// NewContext creates a Context for the given TagMap, taking the set of all notes to be those which have any tag.
+func NewContext(tm tags.TagMap) *Context {
+   all := sets.New[string]()
+   for _, t := range tm {
+      all = all.Union(t.Notes)
+   }
+   return &Context{Tags: tm, All: all}
+}
+
+
+// Tag is an expression matching the notes with a single tag.
+type Tag struct {
+   Name string
+}
+
+// Not is an expression matching the notes which don't match its operand.
+type Not struct {
+   X Expr
+}
+
+// And is an expression matching the notes which match both of its operands.
+type And struct {
+   L, R Expr
+}
+
+// Or is an expression matching the notes which match either of its operands.
+type Or struct {
+   L, R Expr
+}
+
+
+func (e Tag) Eval(ctx *Context) sets.Set[string] {
+   if ctx.Recursive {
+      return ctx.Tags.NotesUnder(e.Name, -1)
+   }
+   if t := ctx.Tags.Get(e.Name); t != nil {
+      return t.Notes.Clone()
+   }
+   return sets.New[string]()
+}
+
+func (e Not) Eval(ctx *Context) sets.Set[string] { return ctx.All.Difference(e.X.Eval(ctx)) }
+func (e And) Eval(ctx *Context) sets.Set[string] { return e.L.Eval(ctx).Intersection(e.R.Eval(ctx)) }
+func (e Or) Eval(ctx *Context) sets.Set[string]  { return e.L.Eval(ctx).Union(e.R.Eval(ctx)) }
+
+func (e Tag) String() string {
+   if strings.ContainsAny(e.Name, " \t\n()\"") || keyword(e.Name) != "" || e.Name == "" {
+      return fmt.Sprintf("%q", e.Name)
+   }
+   return e.Name
+}
+func (e Not) String() string { return "NOT " + e.X.String() }
+func (e And) String() string { return "(" + e.L.String() + " AND " + e.R.String() + ")" }
+func (e Or) String() string  { return "(" + e.L.String() + " OR " + e.R.String() + ")" }
+
+
+// Eval is a convenience function which parses and evaluates a query in one go.
+func Eval(q string, ctx *Context) (sets.Set[string], error) {
+   e, err := Parse(q)
+   if err != nil {
+      return nil, err
+   }
+   return e.Eval(ctx), nil
+}
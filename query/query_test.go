@@ -0,0 +1,72 @@
+package query
+
+import (
+   "errors"
+   "testing"
+
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_Parse(t *testing.T) {
+   for q, expected := range map[string]string{
+      "music": "music",
+      "music and guitar or banjo": "((music AND guitar) OR banjo)",
+      "music AND (guitar OR banjo) AND NOT draft": "((music AND (guitar OR banjo)) AND NOT draft)",
+      "NOT not x": "NOT NOT x",
+      `"stringed instruments" OR "and"`: `("stringed instruments" OR "and")`,
+   } {
+      e, err := Parse(q)
+      if assert.Nil(t, err, q) {
+         assert.Equal(t, expected, e.String(), q)
+      }
+   }
+
+   for _, q := range []string{"", "music AND", "(music", "music)", "music guitar", `"music`, "AND music"} {
+      _, err := Parse(q)
+      var syntaxErr *SyntaxError
+      assert.True(t, errors.As(err, &syntaxErr), q)
+   }
+}
+
+
+func Test_Eval(t *testing.T) {
+   tm := tags.TagMap{}
+   for _, tag := range []*tags.Tag{
+      {Name: "Music", Aliases: []string{"Tunes"}, Notes: sets.New("A", "B", "C")},
+      {Name: "Guitar", Parents: sets.New("Instrument"), Notes: sets.New("A", "D")},
+      {Name: "Banjo", Parents: sets.New("Instrument"), Notes: sets.New("B")},
+      {Name: "Bass Guitar", Parents: sets.New("Guitar"), Notes: sets.New("E")},
+      {Name: "Instrument", Notes: sets.New[string]()},
+      {Name: "Draft", Notes: sets.New("A", "E")},
+   } {
+      for _, name := range append([]string{tag.Name}, tag.Aliases...) {
+         if err := tm.Add(name, tag); err != nil {
+            t.Skipf("Error adding tag to TagMap: %v", err)
+         }
+      }
+   }
+   ctx := NewContext(tm)
+
+   for q, expected := range map[string]sets.Set[string]{
+      "Tunes": sets.New("A", "B", "C"),
+      "Music AND (Guitar OR Banjo)": sets.New("A", "B"),
+      "Music AND (Guitar OR Banjo) AND NOT Draft": sets.New("B"),
+      "NOT Music": sets.New("D", "E"),
+      "Nonexistent": sets.New[string](),
+      "Instrument": sets.New[string](),
+   } {
+      notes, err := Eval(q, ctx)
+      if assert.Nil(t, err, q) {
+         assert.Equal(t, expected, notes, q)
+      }
+   }
+
+   ctx.Recursive = true
+   notes, err := Eval("Instrument AND NOT Draft", ctx)
+   assert.Nil(t, err)
+   assert.Equal(t, sets.New("B", "D"), notes)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "fmt"
+   "os"
+   "slices"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// refAddCmd adds a named ref to a note, replacing any existing ref with the same name.
+func refAddCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 3 {
+      return errors.New("ref add requires a note ID, a name for the ref, and the ref itself")
+   }
+   path, err := note.FindByID(cmd.Args().Get(0))
+   if err != nil {
+      return err
+   }
+   n, err := note.ReadFile(path)
+   if err != nil {
+      return err
+   }
+   r, err := note.ParseRef(cmd.Args().Get(2))
+   if err != nil {
+      return err
+   }
+   if err := r.Validate(); err != nil {
+      return err
+   }
+   if n.Refs == nil {
+      n.Refs = map[string]note.Ref{}
+   }
+   n.Refs[cmd.Args().Get(1)] = r
+   return n.SaveAs(path)
+}
+
+
+// refLsCmd lists the refs of a note, one per line with its name, kind and value.
+func refLsCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 1 {
+      return errors.New("ref ls requires exactly one note ID")
+   }
+   n, err := readNoteByID(cmd.Args().First())
+   if err != nil {
+      return err
+   }
+   for _, name := range refNames(&n) {
+      fmt.Printf("%s\t%s\t%s\n", name, n.Refs[name].Kind, n.Refs[name])
+   }
+   return nil
+}
+
+
+// refCheckCmd checks the refs of the given notes, or of every note if none are given; that they are well formed, that
+// file refs still exist, and that note and tag refs still resolve.
+func refCheckCmd(ctx context.Context, cmd *cli.Command) error {
+   var files []string
+   if cmd.NArg() == 0 {
+      var err error
+      if files, err = note.Files(); err != nil {
+         return err
+      }
+   }
+   for _, id := range cmd.Args().Slice() {
+      path, err := note.FindByID(id)
+      if err != nil {
+         return err
+      }
+      files = append(files, path)
+   }
+   tm, err := tags.LoadAll()
+   if err != nil {
+      return err
+   }
+
+   problems := 0
+   for _, f := range files {
+      n, err := note.ReadFile(f)
+      if err != nil {
+         return err
+      }
+      for _, name := range refNames(&n) {
+         if err := checkRef(tm, n.Refs[name]); err != nil {
+            fmt.Printf("%s\t%s\t%s\t%s\n", n.ID, name, n.Refs[name], err)
+            problems++
+         }
+      }
+   }
+   if problems > 0 {
+      return fmt.Errorf("%d broken ref(s) found", problems)
+   }
+   return nil
+}
+
+
+// checkRef checks that a ref is well formed, and that whatever it points to exists where that can be checked locally.
+func checkRef(tm tags.TagMap, r note.Ref) error {
+   if err := r.Validate(); err != nil {
+      return err
+   }
+   switch r.Kind {
+      case note.RefFile:
+         if _, err := os.Stat(r.Path()); err != nil {
+            return err
+         }
+      case note.RefNote:
+         if _, err := note.FindByID(r.Value); err != nil {
+            return err
+         }
+      case note.RefTag:
+         if tm.Get(r.Value) == nil {
+            return fmt.Errorf("no such tag: %s", r.Value)
+         }
+   }
+   return nil
+}
+
+
+// refNames returns the names of a note's refs, sorted.
+func refNames(n *note.Note) (names []string) {
+   for name := range n.Refs {
+      names = append(names, name)
+   }
+   slices.Sort(names)
+   return
+}
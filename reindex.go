@@ -0,0 +1,38 @@
+package main
+
+import (
+   "context"
+   "fmt"
+
+   "github.com/omnikron13/zelkata/links"
+   "github.com/omnikron13/zelkata/search"
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// reindexCmd rebuilds the derived indexes from scratch from the note files; the Notes sets of every tag, the
+// search index, and the link index.
+func reindexCmd(ctx context.Context, cmd *cli.Command) error {
+   tm, err := tags.LoadAll()
+   if err != nil {
+      return err
+   }
+   if err := tm.Reindex(); err != nil {
+      return err
+   }
+   if err := tm.Save(); err != nil {
+      return err
+   }
+
+   ix, err := search.Rebuild()
+   if err != nil {
+      return err
+   }
+   if _, err := links.Rebuild(); err != nil {
+      return err
+   }
+   fmt.Printf("indexed %d notes\n", len(ix.Docs))
+   return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "fmt"
+   "strings"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/search"
+
+   "github.com/charmbracelet/lipgloss"
+   "github.com/urfave/cli/v3"
+)
+
+// highlightStyle is used to pick out the matching terms in search result snippets.
+var highlightStyle = lipgloss.NewStyle().Bold(true).Reverse(true)
+
+
+// highlight renders a single matching term in the highlightStyle.
+func highlight(s string) string {
+   return highlightStyle.Render(s)
+}
+
+
+// searchCmd runs a full-text search over the notes, printing the best matches with a snippet of each.
+func searchCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() < 1 {
+      return errors.New("search requires at least one search term")
+   }
+   terms := cmd.Args().Slice()
+   ix, err := search.Load()
+   if err != nil {
+      return err
+   }
+
+   shown := 0
+   for _, r := range ix.Search(strings.Join(terms, " ")) {
+      if limit := int(cmd.Int("limit")); limit > 0 && shown >= limit {
+         break
+      }
+      // The index can lag behind notes being removed, so anything which no longer exists is just skipped over
+      path, err := note.FindByID(r.ID)
+      if err != nil {
+         continue
+      }
+      n, err := note.ReadFile(path)
+      if err != nil {
+         return err
+      }
+      fmt.Printf("%s\t%s\t(%.2f)\n", n.ID, n.DisplayTitle(), r.Score)
+      fmt.Printf("   %s\n", search.Snippet(n.Body, terms, int(cmd.Int("width")), highlight))
+      shown++
+   }
+   return nil
+}
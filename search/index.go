@@ -0,0 +1,205 @@
+// Package search provides full-text search over notes, backed by a persistent inverted index. The index is derived
+// data, rebuilt from the note files whenever necessary, so it lives in the state directory rather than alongside the
+// notes themselves.
+package search
+
+import (
+   "bytes"
+   "encoding/gob"
+   "errors"
+   "os"
+   "path/filepath"
+   "slices"
+   "strings"
+   "unicode"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/txn"
+)
+
+// BM25 tuning parameters; these are the commonly used defaults, which there's little reason to deviate from for
+// collections of short notes.
+const (
+   k1 = 1.2
+   b  = 0.75
+)
+
+
+// Index is an inverted index mapping terms to the notes which contain them.
+type Index struct {
+   // Docs maps note IDs to the number of terms in the note, which BM25 needs to normalise for note length.
+   Docs map[string]int
+
+   // Postings maps each term to the IDs of the notes containing it, and how many times it occurs in each.
+   Postings map[string]map[string]int
+
+   // TotalLength is the sum of all the Docs lengths, kept to cheaply calculate the average.
+   TotalLength int
+}
+
+
+// Result is a single search hit.
+type Result struct {
+   // ID is the ID of the matching note.
+   ID string
+
+   // Score is the BM25 relevance score of the note; higher is better.
+   Score float64
+}
+
+
+// New returns a new empty Index.
+func New() *Index {
+   return &Index{Docs: map[string]int{}, Postings: map[string]map[string]int{}}
+}
+
+
+// indexPath returns the path of the on-disk index.
+func indexPath() string {
+   return filepath.Join(paths.State(), "search.idx")
+}
+
+
+// Load reads the on-disk index, returning a new empty Index if there isn't one yet.
+func Load() (*Index, error) {
+   f, err := os.Open(indexPath())
+   if errors.Is(err, os.ErrNotExist) {
+      return New(), nil
+   }
+   if err != nil {
+      return nil, err
+   }
+   defer f.Close()
+   ix := New()
+   if err := gob.NewDecoder(f).Decode(ix); err != nil {
+      return nil, err
+   }
+   return ix, nil
+}
+
+
+// Save writes the index to disk, atomically so that an interrupted save never leaves a truncated index.
+func (ix *Index) Save() error {
+   var b bytes.Buffer
+   if err := gob.NewEncoder(&b).Encode(ix); err != nil {
+      return err
+   }
+   return txn.WriteFile(indexPath(), b.Bytes(), 0600)
+}
+
+
+// Update adds (or re-adds) notes to the on-disk index. It has the signature of a note.SaveHook so that the index is
+// kept up to date as notes are saved.
+func Update(notes ...*note.Note) error {
+   ix, err := Load()
+   if err != nil {
+      return err
+   }
+   for _, n := range notes {
+      ix.Add(n)
+   }
+   return ix.Save()
+}
+
+
+// Rebuild creates a new index from scratch from all the note files, and saves it.
+func Rebuild() (*Index, error) {
+   files, err := note.Files()
+   if err != nil {
+      return nil, err
+   }
+   ix := New()
+   for _, f := range files {
+      n, err := note.ReadFile(f)
+      if err != nil {
+         return nil, err
+      }
+      ix.Add(&n)
+   }
+   return ix, ix.Save()
+}
+
+
+// Add indexes the title and body of a note, replacing anything previously indexed for the same ID.
+func (ix *Index) Add(n *note.Note) {
+   ix.Remove(n.ID)
+   terms := Tokenise(n.DisplayTitle() + "\n" + n.Body)
+   for _, t := range terms {
+      if ix.Postings[t] == nil {
+         ix.Postings[t] = map[string]int{}
+      }
+      ix.Postings[t][n.ID]++
+   }
+   ix.Docs[n.ID] = len(terms)
+   ix.TotalLength += len(terms)
+}
+
+
+// Remove drops a note from the index, if it's there.
+func (ix *Index) Remove(id string) {
+   l, ok := ix.Docs[id]
+   if !ok {
+      return
+   }
+   for t, p := range ix.Postings {
+      delete(p, id)
+      if len(p) == 0 {
+         delete(ix.Postings, t)
+      }
+   }
+   delete(ix.Docs, id)
+   ix.TotalLength -= l
+}
+
+
+// Search returns the notes matching any of the terms in the query, best match first, ranked using BM25.
+func (ix *Index) Search(query string) (results []Result) {
+   if len(ix.Docs) == 0 {
+      return
+   }
+   n := float64(len(ix.Docs))
+   avgLen := float64(ix.TotalLength) / n
+   scores := map[string]float64{}
+   for _, t := range unique(Tokenise(query)) {
+      idf := ix.idf(t)
+      for id, tf := range ix.Postings[t] {
+         f := float64(tf)
+         scores[id] += idf * f * (k1 + 1) / (f + k1 * (1 - b + b * float64(ix.Docs[id]) / avgLen))
+      }
+   }
+   for id, s := range scores {
+      results = append(results, Result{ID: id, Score: s})
+   }
+   slices.SortFunc(results, func(a, b Result) int {
+      if a.Score != b.Score {
+         if a.Score > b.Score { return -1 }
+         return 1
+      }
+      return strings.Compare(a.ID, b.ID)
+   })
+   return
+}
+
+
+// Tokenise splits text into lower case terms, treating anything that isn't a letter or a number as a separator.
+func Tokenise(s string) []string {
+   return strings.FieldsFunc(strings.ToLower(s), isSeparator)
+}
+
+
+// isSeparator reports whether the rune separates terms.
+func isSeparator(r rune) bool {
+   return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+
+// unique returns the distinct strings in a slice, in order of first appearance.
+func unique(s []string) (u []string) {
+   for _, v := range s {
+      if !slices.Contains(u, v) {
+         u = append(u, v)
+      }
+   }
+   return
+}
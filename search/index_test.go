@@ -0,0 +1,50 @@
+package search
+
+import (
+   "testing"
+
+   "github.com/omnikron13/zelkata/note"
+
+   "github.com/stretchr/testify/assert"
+)
+
+
+func Test_Tokenise(t *testing.T) {
+   assert.Equal(t, []string{"a", "test", "note", "with", "münster", "2024"}, Tokenise("A Test-Note, with Münster & 2024!"))
+}
+
+
+func Test_Index(t *testing.T) {
+   ix := New()
+   ix.Add(&note.Note{Meta: note.Meta{ID: "A"}, Body: "Banjos are stringed instruments, much like guitars."})
+   ix.Add(&note.Note{Meta: note.Meta{ID: "B"}, Body: "The guitar. Guitar guitar guitar."})
+   ix.Add(&note.Note{Meta: note.Meta{ID: "C"}, Body: "Flutes are woodwind instruments."})
+
+   t.Run("ranking", func(t *testing.T) {
+      results := ix.Search("guitar")
+      if assert.Len(t, results, 1) {
+         assert.Equal(t, "B", results[0].ID)
+      }
+      results = ix.Search("instruments guitar")
+      ids := []string{}
+      for _, r := range results {
+         ids = append(ids, r.ID)
+      }
+      // A only mentions "guitars", and without stemming that's a different term, so the shorter C beats it
+      assert.Equal(t, []string{"B", "C", "A"}, ids)
+   })
+
+   t.Run("re-add replaces", func(t *testing.T) {
+      ix.Add(&note.Note{Meta: note.Meta{ID: "B"}, Body: "Now about pianos."})
+      assert.Empty(t, ix.Search("guitar"))
+      assert.Equal(t, 3, ix.Docs["B"])
+      assert.Equal(t, 7 + 3 + 4, ix.TotalLength)
+   })
+
+   t.Run("remove", func(t *testing.T) {
+      ix.Remove("B")
+      assert.Empty(t, ix.Search("pianos"))
+      assert.NotContains(t, ix.Postings, "pianos")
+      assert.Equal(t, 7 + 4, ix.TotalLength)
+   })
+}
@@ -0,0 +1,85 @@
+package search
+
+import (
+   "strings"
+   "unicode/utf8"
+
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+// Snippet returns an excerpt of roughly width characters from text, centred loosely around the first occurrence of
+// any of the terms, with every occurrence of a term in the excerpt passed through highlight. Whitespace, including
+// newlines, is collapsed so the excerpt fits on a single line.
+func Snippet(text string, terms []string, width int, highlight func(string) string) string {
+   text = strings.Join(strings.Fields(text), " ")
+   want := sets.New[string]()
+   for _, t := range terms {
+      want.Insert(Tokenise(t)...)
+   }
+   spans := tokenSpans(text)
+
+   // Start a little before the first match, so it has some context, snapping back to the start of a word
+   start := 0
+   for _, s := range spans {
+      if want.Has(strings.ToLower(text[s[0]:s[1]])) {
+         start = s[0]
+         break
+      }
+   }
+   for back := width / 3; back > 0 && start > 0; back-- {
+      _, size := utf8.DecodeLastRuneInString(text[:start])
+      start -= size
+   }
+   for _, s := range spans {
+      if s[0] <= start && start < s[1] {
+         start = s[0]
+         break
+      }
+   }
+   end := start
+   for n := 0; n < width && end < len(text); n++ {
+      _, size := utf8.DecodeRuneInString(text[end:])
+      end += size
+   }
+
+   var sb strings.Builder
+   if start > 0 {
+      sb.WriteString("…")
+   }
+   last := start
+   for _, s := range spans {
+      if s[1] <= start || s[0] >= end || !want.Has(strings.ToLower(text[s[0]:s[1]])) {
+         continue
+      }
+      e := min(s[1], end)
+      sb.WriteString(text[last:s[0]])
+      sb.WriteString(highlight(text[s[0]:e]))
+      last = e
+   }
+   sb.WriteString(text[last:end])
+   if end < len(text) {
+      return strings.TrimRight(sb.String(), " ") + "…"
+   }
+   return sb.String()
+}
+
+
+// tokenSpans returns the start & end byte offsets of each of the terms in text, as Tokenise would split them.
+func tokenSpans(text string) (spans [][2]int) {
+   start := -1
+   for i, r := range text {
+      if isSeparator(r) {
+         if start >= 0 {
+            spans = append(spans, [2]int{start, i})
+            start = -1
+         }
+      } else if start < 0 {
+         start = i
+      }
+   }
+   if start >= 0 {
+      spans = append(spans, [2]int{start, len(text)})
+   }
+   return
+}
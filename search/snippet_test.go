@@ -0,0 +1,25 @@
+package search
+
+import (
+   "testing"
+
+   "github.com/stretchr/testify/assert"
+)
+
+
+func Test_Snippet(t *testing.T) {
+   mark := func(s string) string { return "[" + s + "]" }
+
+   t.Run("short text", func(t *testing.T) {
+      assert.Equal(t, "A [Banjo] and a [banjo].", Snippet("A Banjo\nand a banjo.", []string{"banjo"}, 80, mark))
+   })
+
+   t.Run("windowed", func(t *testing.T) {
+      text := "Lots of waffle before we get to the point, which is that a guitar has strings, and then lots more waffle."
+      assert.Equal(t, "…which is that a [guitar] has strings, and then lots…", Snippet(text, []string{"GUITAR"}, 50, mark))
+   })
+
+   t.Run("no match", func(t *testing.T) {
+      assert.Equal(t, "Nothing to see…", Snippet("Nothing to see here.", []string{"banjo"}, 14, mark))
+   })
+}
@@ -0,0 +1,112 @@
+package search
+
+import (
+   "cmp"
+   "math"
+   "slices"
+   "strings"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/tags"
+)
+
+// minScore is the lowest score, relative to the best suggestion, for a tag to be suggested at all; much weaker
+// suggestions are mostly noise from notes sharing little more than common words.
+const minScore = 0.25
+
+// similarNotes is how many of the notes most similar to a note are taken into account when suggesting tags for it.
+const similarNotes = 20
+
+
+// TagSuggestion is a tag suggested for a note.
+type TagSuggestion struct {
+   // Tag is the suggested tag.
+   Tag *tags.Tag
+
+   // Score is how strongly the tag is suggested, relative to the best suggestion, which scores 1.
+   Score float64
+}
+
+
+// SuggestTags suggests up to limit tags for a note the note doesn't already have (and which aren't virtual), best
+// first. Tags are suggested for being on the notes most similar to it, as ranked by searching the index for the note's
+// own text, and for their descriptions sharing terms with it, weighted by how rare those terms are. It is entirely
+// offline, working only from the index and the tags.
+func SuggestTags(ix *Index, tm tags.TagMap, n *note.Note, limit int) (suggestions []TagSuggestion) {
+   text := n.DisplayTitle() + "\n" + n.Body
+   scores := map[*tags.Tag]float64{}
+
+   noteTags := map[string][]*tags.Tag{}
+   all := map[*tags.Tag]bool{}
+   for _, t := range tm {
+      if all[t] {
+         continue
+      }
+      all[t] = true
+      for id := range t.Notes {
+         noteTags[id] = append(noteTags[id], t)
+      }
+   }
+
+   similar := 0
+   for _, r := range ix.Search(text) {
+      if r.ID == n.ID {
+         continue
+      }
+      for _, t := range noteTags[r.ID] {
+         scores[t] += r.Score
+      }
+      if similar++; similar >= similarNotes {
+         break
+      }
+   }
+
+   terms := map[string]bool{}
+   for _, term := range Tokenise(text) {
+      terms[term] = true
+   }
+   for t := range all {
+      for _, term := range unique(Tokenise(t.Description + " " + t.Name)) {
+         if terms[term] {
+            scores[t] += ix.idf(term)
+         }
+      }
+   }
+
+   // A note may have a tag by any of its aliases, so compare the tags themselves rather than their names
+   has := map[*tags.Tag]bool{}
+   for name := range n.Tags {
+      if t := tm.Get(name); t != nil {
+         has[t] = true
+      }
+   }
+
+   best := 0.0
+   for t, s := range scores {
+      if t.Virtual || has[t] || s <= 0 {
+         continue
+      }
+      suggestions = append(suggestions, TagSuggestion{Tag: t, Score: s})
+      best = max(best, s)
+   }
+   slices.SortFunc(suggestions, func(a, b TagSuggestion) int {
+      return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Tag.Name, b.Tag.Name))
+   })
+   for i := range suggestions {
+      suggestions[i].Score /= best
+   }
+   suggestions = slices.DeleteFunc(suggestions, func(s TagSuggestion) bool { return s.Score < minScore })
+   if limit > 0 && len(suggestions) > limit {
+      suggestions = suggestions[:limit]
+   }
+   return
+}
+
+
+// idf returns the inverse document frequency of a term in the index, as used by BM25; a term in no notes at all is
+// treated as if it were in one, as it is still evidently of some interest.
+func (ix *Index) idf(term string) float64 {
+   n := float64(max(len(ix.Docs), 1))
+   df := float64(max(len(ix.Postings[term]), 1))
+   return math.Log(1 + (n - df + 0.5) / (df + 0.5))
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+   "testing"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_SuggestTags(t *testing.T) {
+   ix := New()
+   ix.Add(&note.Note{Meta: note.Meta{ID: "A"}, Body: "Tuning a guitar by ear, string by string."})
+   ix.Add(&note.Note{Meta: note.Meta{ID: "B"}, Body: "Guitar chord shapes for beginners."})
+   ix.Add(&note.Note{Meta: note.Meta{ID: "C"}, Body: "Sourdough needs a lively starter."})
+
+   tm := tags.TagMap{}
+   for _, tag := range []*tags.Tag{
+      {Name: "Guitar", Notes: sets.New("A", "B")},
+      {Name: "Music", Notes: sets.New("A")},
+      {Name: "Baking", Notes: sets.New("C")},
+      {Name: "Theory", Description: "Harmony, scales and chord construction"},
+   } {
+      if err := tm.Add(tag.Name, tag); err != nil {
+         t.Skipf("Error adding tag to TagMap: %v", err)
+      }
+   }
+
+   n := &note.Note{Meta: note.Meta{ID: "D", Tags: sets.New("Music")}, Body: "A new guitar chord I learnt."}
+   suggestions := SuggestTags(ix, tm, n, 0)
+   names := []string{}
+   for _, s := range suggestions {
+      names = append(names, s.Tag.Name)
+   }
+   // Music is already on the note, and Baking is only weakly suggested by the note on it sharing the word "a"
+   assert.Equal(t, []string{"Guitar", "Theory"}, names)
+   assert.Equal(t, 1.0, suggestions[0].Score)
+   assert.Less(t, suggestions[1].Score, 1.0)
+
+   // A tag the note has by one of its aliases is still not suggested again
+   guitar := tm.Get("Guitar")
+   guitar.Aliases = []string{"Guitars"}
+   assert.Nil(t, tm.Add("Guitars", guitar))
+   aliased := &note.Note{Meta: note.Meta{ID: "D", Tags: sets.New("Guitars")}, Body: n.Body}
+   for _, s := range SuggestTags(ix, tm, aliased, 0) {
+      assert.NotSame(t, guitar, s.Tag)
+   }
+
+   assert.Len(t, SuggestTags(ix, tm, n, 1), 1)
+   assert.Empty(t, SuggestTags(ix, tm, &note.Note{Body: "Nothing relevant."}, 0))
+}
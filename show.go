@@ -0,0 +1,41 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "fmt"
+   "os"
+
+   "github.com/omnikron13/zelkata/note"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// showCmd prints a single note, resolved from a full or partial ID. By default only the body is printed, as that's
+// what is of interest to a human reader; the front matter can be included to see the note as it is stored on disk.
+func showCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 1 {
+      return errors.New("show requires exactly one note ID")
+   }
+   path, err := note.FindByID(cmd.Args().First())
+   if err != nil {
+      return err
+   }
+
+   if cmd.Bool("front-matter") {
+      b, err := os.ReadFile(path)
+      if err != nil {
+         return err
+      }
+      _, err = os.Stdout.Write(b)
+      return err
+   }
+
+   n, err := note.ReadFile(path)
+   if err != nil {
+      return err
+   }
+   fmt.Print(n.Body)
+   return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "fmt"
+
+   "github.com/omnikron13/zelkata/lock"
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// tagRenameCmd renames a tag, rewriting every note and tag which refers to it.
+func tagRenameCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 2 {
+      return errors.New("rename requires the old and new tag names")
+   }
+   oldName, newName := cmd.Args().Get(0), cmd.Args().Get(1)
+   if err := tags.Rename(oldName, newName, cmd.Bool("keep-alias")); err != nil {
+      return err
+   }
+   fmt.Printf("renamed %s to %s\n", oldName, newName)
+   return nil
+}
+
+
+// tagMergeCmd merges one tag into another, rewriting every note and tag which refers to the first.
+func tagMergeCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() != 2 {
+      return errors.New("merge requires the names of the tag to merge, and the tag to merge it into")
+   }
+   from, into := cmd.Args().Get(0), cmd.Args().Get(1)
+   if err := tags.Merge(from, into, cmd.Bool("keep-alias")); err != nil {
+      return err
+   }
+   fmt.Printf("merged %s into %s\n", from, into)
+   return nil
+}
+
+
+// tagEdit returns a cli action which loads all the tags, applies a curation function from the TagMap to the command's
+// arguments, and saves whichever tag it changed.
+func tagEdit(usage string, edit func(tm *tags.TagMap, args []string) (*tags.Tag, error), nargs int) cli.ActionFunc {
+   return locked(lock.Exclusive, func(ctx context.Context, cmd *cli.Command) error {
+      if cmd.NArg() != nargs {
+         return fmt.Errorf("expected arguments: %s", usage)
+      }
+      tm, err := tags.LoadAll()
+      if err != nil {
+         return err
+      }
+      t, err := edit(&tm, cmd.Args().Slice())
+      if err != nil {
+         return err
+      }
+      return t.Save()
+   })
+}
+
+
+// tagEditCmds are the subcommands for curating the details of individual tags.
+var tagEditCmds = []*cli.Command{
+   {
+      Name: "set-description",
+      Usage: "set the description of a tag",
+      ArgsUsage: "<tag> <description>",
+      Action: tagEdit("<tag> <description>", func(tm *tags.TagMap, a []string) (*tags.Tag, error) {
+         return tm.SetDescription(a[0], a[1])
+      }, 2),
+   },
+   {
+      Name: "set-icon",
+      Usage: "set the icon of a tag",
+      ArgsUsage: "<tag> <icon>",
+      Action: tagEdit("<tag> <icon>", func(tm *tags.TagMap, a []string) (*tags.Tag, error) {
+         return tm.SetIcon(a[0], a[1])
+      }, 2),
+   },
+   {
+      Name: "alias",
+      Usage: "manage the aliases of a tag",
+      Commands: []*cli.Command{
+         {
+            Name: "add",
+            Usage: "add an alias to a tag",
+            ArgsUsage: "<tag> <alias>",
+            Action: tagEdit("<tag> <alias>", func(tm *tags.TagMap, a []string) (*tags.Tag, error) {
+               return tm.AddAlias(a[0], a[1])
+            }, 2),
+         },
+         {
+            Name: "rm",
+            Usage: "remove an alias from a tag",
+            ArgsUsage: "<tag> <alias>",
+            Action: tagEdit("<tag> <alias>", func(tm *tags.TagMap, a []string) (*tags.Tag, error) {
+               return tm.RemoveAlias(a[0], a[1])
+            }, 2),
+         },
+      },
+   },
+   {
+      Name: "parent",
+      Usage: "manage the parents of a tag",
+      Commands: []*cli.Command{
+         {
+            Name: "add",
+            Usage: "add a parent to a tag",
+            ArgsUsage: "<tag> <parent>",
+            Action: tagEdit("<tag> <parent>", func(tm *tags.TagMap, a []string) (*tags.Tag, error) {
+               return tm.AddParent(a[0], a[1])
+            }, 2),
+         },
+         {
+            Name: "rm",
+            Usage: "remove a parent from a tag",
+            ArgsUsage: "<tag> <parent>",
+            Action: tagEdit("<tag> <parent>", func(tm *tags.TagMap, a []string) (*tags.Tag, error) {
+               return tm.RemoveParent(a[0], a[1])
+            }, 2),
+         },
+      },
+   },
+   {
+      Name: "relate",
+      Usage: "record how a tag relates to another",
+      ArgsUsage: "<tag> <other> <description>",
+      Action: tagEdit("<tag> <other> <description>", func(tm *tags.TagMap, a []string) (*tags.Tag, error) {
+         return tm.Relate(a[0], a[1], a[2])
+      }, 3),
+   },
+}
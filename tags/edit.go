@@ -0,0 +1,146 @@
+package tags
+
+import (
+   "fmt"
+   "slices"
+
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+// The following functions are for curating the tags in a TagMap. Each validates the change against the rest of the
+// TagMap before making it, and returns the tag which was changed so that the caller can save it.
+
+
+// mustGet returns the named tag, or an error if it doesn't exist.
+func (m *TagMap) mustGet(name string) (*Tag, error) {
+   if t := m.Get(name); t != nil {
+      return t, nil
+   }
+   return nil, fmt.Errorf("no such tag: %s", name)
+}
+
+
+// SetDescription sets the description of the named tag.
+func (m *TagMap) SetDescription(name, description string) (*Tag, error) {
+   t, err := m.mustGet(name)
+   if err != nil {
+      return nil, err
+   }
+   t.Description = description
+   return t, nil
+}
+
+
+// SetIcon sets the icon of the named tag.
+func (m *TagMap) SetIcon(name, icon string) (*Tag, error) {
+   t, err := m.mustGet(name)
+   if err != nil {
+      return nil, err
+   }
+   t.Icon = icon
+   return t, nil
+}
+
+
+// AddAlias adds an alias to the named tag, failing if the alias is already in use as the name or alias of any tag.
+func (m *TagMap) AddAlias(name, alias string) (*Tag, error) {
+   t, err := m.mustGet(name)
+   if err != nil {
+      return nil, err
+   }
+   if other := m.Get(alias); other != nil {
+      return nil, fmt.Errorf("%q is already in use by tag %q", alias, other.Name)
+   }
+   if err := m.Add(alias, t); err != nil {
+      return nil, err
+   }
+   t.Aliases = append(t.Aliases, alias)
+   return t, nil
+}
+
+
+// RemoveAlias removes an alias from the named tag.
+func (m *TagMap) RemoveAlias(name, alias string) (*Tag, error) {
+   t, err := m.mustGet(name)
+   if err != nil {
+      return nil, err
+   }
+   i := slices.IndexFunc(t.Aliases, func(a string) bool { return normaliseName(a) == normaliseName(alias) })
+   if i < 0 {
+      return nil, fmt.Errorf("%q is not an alias of tag %q", alias, t.Name)
+   }
+   t.Aliases = slices.Delete(t.Aliases, i, i+1)
+   delete(*m, normaliseName(alias))
+   return t, nil
+}
+
+
+// AddParent adds a parent to the named tag. The parent must exist, and must not already be below the tag in the
+// hierarchy, as that would create a cycle.
+func (m *TagMap) AddParent(name, parent string) (*Tag, error) {
+   t, err := m.mustGet(name)
+   if err != nil {
+      return nil, err
+   }
+   p, err := m.mustGet(parent)
+   if err != nil {
+      return nil, err
+   }
+   if p == t || slices.Contains(m.Descendants(t.Name), p) {
+      return nil, fmt.Errorf("making %q a parent of %q would create a cycle", p.Name, t.Name)
+   }
+   if t.Parents == nil {
+      t.Parents = sets.New[string]()
+   }
+   t.Parents.Insert(p.Name)
+   return t, nil
+}
+
+
+// RemoveParent removes a parent from the named tag, by any of the parent's names.
+func (m *TagMap) RemoveParent(name, parent string) (*Tag, error) {
+   t, err := m.mustGet(name)
+   if err != nil {
+      return nil, err
+   }
+   p := m.Get(parent)
+   removed := false
+   for name := range t.Parents {
+      if name == parent || (p != nil && m.Get(name) == p) {
+         t.Parents.Delete(name)
+         removed = true
+      }
+   }
+   if !removed {
+      return nil, fmt.Errorf("%q is not a parent of tag %q", parent, t.Name)
+   }
+   return t, nil
+}
+
+
+// Relate records a relation from the named tag to another, along with a short description of how they are related,
+// replacing any existing description of the relation.
+func (m *TagMap) Relate(name, other, description string) (*Tag, error) {
+   t, err := m.mustGet(name)
+   if err != nil {
+      return nil, err
+   }
+   o, err := m.mustGet(other)
+   if err != nil {
+      return nil, err
+   }
+   if o == t {
+      return nil, fmt.Errorf("cannot relate tag %q to itself", t.Name)
+   }
+   for r := range t.Relations {
+      if m.Get(r) == o {
+         delete(t.Relations, r)
+      }
+   }
+   if t.Relations == nil {
+      t.Relations = map[string]string{}
+   }
+   t.Relations[o.Name] = description
+   return t, nil
+}
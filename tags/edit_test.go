@@ -0,0 +1,98 @@
+package tags
+
+import (
+   "testing"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+// testTagMap builds a small TagMap for testing the curation functions against.
+func testTagMap(t *testing.T) TagMap {
+   tm := TagMap{}
+   for _, tag := range []*Tag{
+      {Name: "Music", Aliases: []string{"Tunes"}},
+      {Name: "Instrument", Parents: sets.New("Music")},
+      {Name: "Guitar", Parents: sets.New("Instrument")},
+      {Name: "Banjo"},
+   } {
+      for _, name := range append([]string{tag.Name}, tag.Aliases...) {
+         if err := tm.Add(name, tag); err != nil {
+            t.Skipf("Error adding tag to TagMap: %v", err)
+         }
+      }
+   }
+   return tm
+}
+
+
+func Test_TagMap_SetDescription(t *testing.T) {
+   tm := testTagMap(t)
+   tag, err := tm.SetDescription("Tunes", "Organised sound")
+   assert.Nil(t, err)
+   assert.Equal(t, "Organised sound", tag.Description)
+   _, err = tm.SetDescription("Nonexistent", "")
+   assert.NotNil(t, err)
+}
+
+
+func Test_TagMap_Aliases(t *testing.T) {
+   tm := testTagMap(t)
+
+   tag, err := tm.AddAlias("Guitar", "Axe")
+   assert.Nil(t, err)
+   assert.Equal(t, []string{"Axe"}, tag.Aliases)
+   assert.Equal(t, tag, tm.Get("Axe"))
+
+   _, err = tm.AddAlias("Banjo", "Tunes")
+   assert.ErrorContains(t, err, "Music")
+   _, err = tm.AddAlias("Banjo", "Guitar")
+   assert.NotNil(t, err)
+
+   tag, err = tm.RemoveAlias("Guitar", "Axe")
+   assert.Nil(t, err)
+   assert.Empty(t, tag.Aliases)
+   assert.Nil(t, tm.Get("Axe"))
+   _, err = tm.RemoveAlias("Guitar", "Axe")
+   assert.NotNil(t, err)
+}
+
+
+func Test_TagMap_Parents(t *testing.T) {
+   tm := testTagMap(t)
+
+   tag, err := tm.AddParent("Banjo", "Instrument")
+   assert.Nil(t, err)
+   assert.Equal(t, sets.New("Instrument"), tag.Parents)
+
+   _, err = tm.AddParent("Music", "Guitar")
+   assert.ErrorContains(t, err, "cycle")
+   _, err = tm.AddParent("Music", "Tunes")
+   assert.ErrorContains(t, err, "cycle")
+   _, err = tm.AddParent("Banjo", "Nonexistent")
+   assert.NotNil(t, err)
+
+   tag, err = tm.RemoveParent("Instrument", "Tunes")
+   assert.Nil(t, err)
+   assert.Empty(t, tag.Parents)
+   _, err = tm.RemoveParent("Instrument", "Music")
+   assert.NotNil(t, err)
+}
+
+
+func Test_TagMap_Relate(t *testing.T) {
+   tm := testTagMap(t)
+
+   tag, err := tm.Relate("Banjo", "Guitar", "both stringed")
+   assert.Nil(t, err)
+   assert.Equal(t, map[string]string{"Guitar": "both stringed"}, tag.Relations)
+   tag, err = tm.Relate("Banjo", "Guitar", "both plucked")
+   assert.Nil(t, err)
+   assert.Equal(t, map[string]string{"Guitar": "both plucked"}, tag.Relations)
+
+   _, err = tm.Relate("Banjo", "Banjo", "")
+   assert.NotNil(t, err)
+   _, err = tm.Relate("Banjo", "Nonexistent", "")
+   assert.NotNil(t, err)
+}
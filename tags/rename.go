@@ -0,0 +1,263 @@
+package tags
+
+import (
+   "errors"
+   "fmt"
+   "slices"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/txn"
+
+   "gopkg.in/yaml.v3"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+// Rename renames a tag, rewriting every note which has it, updating any tags which refer to it as a parent or
+// relation, and moving the tag file to its new name. If keepAlias is set the old name is kept as an alias, so it can
+// continue to be used when tagging new notes. Either every file is updated, or none are.
+func Rename(oldName, newName string, keepAlias bool) error {
+   tm, err := LoadAll()
+   if err != nil {
+      return err
+   }
+   tag := tm.Get(oldName)
+   if tag == nil {
+      return fmt.Errorf("no such tag: %s", oldName)
+   }
+   if err := checkRetargetable(tag); err != nil {
+      return err
+   }
+   if other := tm.Get(newName); other != nil && other != tag {
+      return fmt.Errorf("tag %q already exists, perhaps you want to merge the tags instead?", other.Name)
+   }
+   oldPath, err := tag.Path()
+   if err != nil {
+      return err
+   }
+
+   tx := txn.New()
+   // Everything referring to the tag has to be found before it changes name, while it still resolves the same way
+   if err := retargetNotes(tx, tm, tag, newName); err != nil {
+      return err
+   }
+   changed := tm.retarget(tag, newName)
+
+   oldName = tag.Name
+   tag.Name = newName
+   tag.file = ""
+   tag.Aliases = slices.DeleteFunc(tag.Aliases, func(a string) bool { return normaliseName(a) == normaliseName(newName) })
+   if keepAlias {
+      tag.Aliases = append(tag.Aliases, oldName)
+   }
+
+   if err := writeTags(tx, append(changed, tag)...); err != nil {
+      return err
+   }
+   if newPath, _ := tag.Path(); newPath != oldPath {
+      tx.Remove(oldPath)
+   }
+   return tx.Commit()
+}
+
+
+// Merge merges the tag from into the tag into, which is left with the notes, aliases, parents, and relations of both.
+// Notes with the from tag are rewritten to have the into tag instead, tags referring to it are updated likewise, and
+// its tag file is removed. If keepAlias is set the from tag's name is kept as an alias of the into tag. As with Rename
+// either every file is updated, or none are.
+func Merge(from, into string, keepAlias bool) error {
+   tm, err := LoadAll()
+   if err != nil {
+      return err
+   }
+   src, dst := tm.Get(from), tm.Get(into)
+   if src == nil {
+      return fmt.Errorf("no such tag: %s", from)
+   }
+   if dst == nil {
+      return fmt.Errorf("no such tag: %s", into)
+   }
+   if src == dst {
+      return errors.New("cannot merge a tag into itself")
+   }
+   for _, t := range []*Tag{src, dst} {
+      if err := checkRetargetable(t); err != nil {
+         return err
+      }
+   }
+   srcPath, err := src.Path()
+   if err != nil {
+      return err
+   }
+
+   tx := txn.New()
+   if err := retargetNotes(tx, tm, src, dst.Name); err != nil {
+      return err
+   }
+   changed := tm.retarget(src, dst.Name)
+   dst.merge(src, keepAlias)
+
+   if err := writeTags(tx, append(slices.DeleteFunc(changed, func(t *Tag) bool { return t == src }), dst)...); err != nil {
+      return err
+   }
+   tx.Remove(srcPath)
+   return tx.Commit()
+}
+
+
+// checkRetargetable returns an error wrapping ErrVirtual if the tag is virtual, as built in tags all are. Which notes
+// have a virtual tag is decided by its rule, so renaming or merging it would mean writing it into the notes directly.
+func checkRetargetable(t *Tag) error {
+   if t.Virtual || t.builtin {
+      return fmt.Errorf("cannot rename or merge %s: %w", t.Name, ErrVirtual)
+   }
+   return nil
+}
+
+
+// merge folds everything from src into t. The src tag's name (if keepAlias) and aliases become aliases of t, its notes
+// and parents are added to t's, and its relations are added where t doesn't already have one with the same tag. The
+// description & icon are only taken from src if t doesn't have its own.
+func (t *Tag) merge(src *Tag, keepAlias bool) {
+   if t.Notes == nil {
+      t.Notes = sets.New[string]()
+   }
+   t.Notes = t.Notes.Union(src.Notes)
+
+   aliases := src.Aliases
+   if keepAlias {
+      aliases = append([]string{src.Name}, aliases...)
+   }
+   for _, a := range aliases {
+      if normaliseName(a) == normaliseName(t.Name) {
+         continue
+      }
+      if !slices.ContainsFunc(t.Aliases, func(b string) bool { return normaliseName(a) == normaliseName(b) }) {
+         t.Aliases = append(t.Aliases, a)
+      }
+   }
+
+   for p := range src.Parents {
+      if normaliseName(p) == normaliseName(t.Name) || normaliseName(p) == normaliseName(src.Name) {
+         continue
+      }
+      if t.Parents == nil {
+         t.Parents = sets.New[string]()
+      }
+      t.Parents.Insert(p)
+   }
+
+   for r, desc := range src.Relations {
+      if _, exists := t.Relations[r]; exists || normaliseName(r) == normaliseName(t.Name) {
+         continue
+      }
+      if t.Relations == nil {
+         t.Relations = map[string]string{}
+      }
+      t.Relations[r] = desc
+   }
+
+   if t.Description == "" {
+      t.Description = src.Description
+   }
+   if t.Icon == "" {
+      t.Icon = src.Icon
+   }
+}
+
+
+// retarget updates the parents and relations of every tag in the TagMap which refer to the from tag (by any of its
+// names) to refer to the tag named to instead, returning the tags which changed. A tag which would end up being its
+// own parent or relation has that reference dropped instead.
+func (m *TagMap) retarget(from *Tag, to string) (changed []*Tag) {
+   for name, t := range *m {
+      if name != normaliseName(t.Name) {
+         continue
+      }
+      dirty := false
+      for p := range t.Parents {
+         if m.Get(p) != from {
+            continue
+         }
+         t.Parents.Delete(p)
+         if normaliseName(to) != normaliseName(t.Name) {
+            t.Parents.Insert(to)
+         }
+         dirty = true
+      }
+      for r, desc := range t.Relations {
+         if m.Get(r) != from {
+            continue
+         }
+         delete(t.Relations, r)
+         if normaliseName(to) != normaliseName(t.Name) {
+            t.Relations[to] = desc
+         }
+         dirty = true
+      }
+      if dirty {
+         changed = append(changed, t)
+      }
+   }
+   return
+}
+
+
+// retargetNote replaces any of the note's tags which resolve to the from tag with the tag named to, reporting whether
+// anything was changed.
+func retargetNote(tm TagMap, n *note.Note, from *Tag, to string) bool {
+   dirty := false
+   for name := range n.Tags {
+      if tm.Get(name) == from {
+         n.Tags.Delete(name)
+         dirty = true
+      }
+   }
+   if dirty {
+      n.Tags.Insert(to)
+   }
+   return dirty
+}
+
+
+// retargetNotes queues rewriting every note with the from tag to have the tag named to instead. Every note file is
+// checked rather than trusting from.Notes, as the note files are the canonical record of which notes have which tags.
+func retargetNotes(tx *txn.Txn, tm TagMap, from *Tag, to string) error {
+   files, err := note.Files()
+   if err != nil {
+      return err
+   }
+   for _, f := range files {
+      n, err := note.ReadFile(f)
+      if err != nil {
+         return fmt.Errorf("error reading note %s: %w", f, err)
+      }
+      if !retargetNote(tm, &n, from, to) {
+         continue
+      }
+      if err := n.SaveTx(tx, f); err != nil {
+         return err
+      }
+   }
+   return nil
+}
+
+
+// writeTags queues writing each of the tags to its tag file, other than built in tags, as TagMap.Save does.
+func writeTags(tx *txn.Txn, tags ...*Tag) error {
+   for _, t := range tags {
+      if t.builtin {
+         continue
+      }
+      path, err := t.Path()
+      if err != nil {
+         return err
+      }
+      b, err := yaml.Marshal(t)
+      if err != nil {
+         return err
+      }
+      tx.Write(path, b, 0600)
+   }
+   return nil
+}
@@ -0,0 +1,130 @@
+package tags
+
+import (
+   "path/filepath"
+   "testing"
+
+   "github.com/omnikron13/zelkata/internal/testenv"
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_Tag_merge(t *testing.T) {
+   dst := &Tag{
+      Name: "Guitar",
+      Aliases: []string{"Guitars"},
+      Notes: sets.New("A"),
+      Relations: map[string]string{"Banjo": "both stringed"},
+   }
+   src := &Tag{
+      Name: "Gitar",
+      Description: "Typo of guitar",
+      Icon: "󰋄",
+      Aliases: []string{"Guitars", "Geetar", "Guitar"},
+      Notes: sets.New("A", "B"),
+      Parents: sets.New("Instrument", "Gitar"),
+      Relations: map[string]string{"Banjo": "ignored", "Ukulele": "smaller cousin"},
+   }
+   dst.merge(src, true)
+   assert.Equal(t, &Tag{
+      Name: "Guitar",
+      Description: "Typo of guitar",
+      Icon: "󰋄",
+      Aliases: []string{"Guitars", "Gitar", "Geetar"},
+      Notes: sets.New("A", "B"),
+      Parents: sets.New("Instrument"),
+      Relations: map[string]string{"Banjo": "both stringed", "Ukulele": "smaller cousin"},
+   }, dst)
+}
+
+
+func Test_TagMap_retarget(t *testing.T) {
+   old := &Tag{Name: "Old", Aliases: []string{"Older"}}
+   child := &Tag{Name: "Child", Parents: sets.New("Older", "Other")}
+   relative := &Tag{Name: "Relative", Relations: map[string]string{"Old": "why not"}}
+   target := &Tag{Name: "New", Parents: sets.New("Old")}
+   untouched := &Tag{Name: "Untouched", Parents: sets.New("Other")}
+   tm := TagMap{}
+   for _, tag := range []*Tag{old, child, relative, target, untouched} {
+      for _, name := range append([]string{tag.Name}, tag.Aliases...) {
+         if err := tm.Add(name, tag); err != nil {
+            t.Skipf("Error adding tag to TagMap: %v", err)
+         }
+      }
+   }
+
+   changed := tm.retarget(old, "New")
+   assert.ElementsMatch(t, []*Tag{child, relative, target}, changed)
+   assert.Equal(t, sets.New("New", "Other"), child.Parents)
+   assert.Equal(t, map[string]string{"New": "why not"}, relative.Relations)
+   // A tag can't become its own parent
+   assert.Equal(t, sets.New[string](), target.Parents)
+   assert.Equal(t, sets.New("Other"), untouched.Parents)
+}
+
+
+func Test_retargetNote(t *testing.T) {
+   old := &Tag{Name: "Old", Aliases: []string{"Older"}}
+   tm := TagMap{}
+   for _, name := range []string{"Old", "Older"} {
+      if err := tm.Add(name, old); err != nil {
+         t.Skipf("Error adding tag to TagMap: %v", err)
+      }
+   }
+
+   n := note.Note{Meta: note.Meta{Tags: sets.New("Older", "Old", "Other")}}
+   assert.True(t, retargetNote(tm, &n, old, "New"))
+   assert.Equal(t, sets.New("New", "Other"), n.Tags)
+
+   n = note.Note{Meta: note.Meta{Tags: sets.New("Other")}}
+   assert.False(t, retargetNote(tm, &n, old, "New"))
+   assert.Equal(t, sets.New("Other"), n.Tags)
+}
+
+
+func Test_Rename_Merge_virtual(t *testing.T) {
+   testenv.Setup(t)
+   n := note.New("Plain.\n")
+   n.Tags = sets.New("Plain")
+   if err := n.Save(); err != nil {
+      t.Fatalf("Failed to save note: %s", err)
+   }
+   if err := Retag(n.ID, nil, n.Tags); err != nil {
+      t.Fatalf("Failed to tag note: %s", err)
+   }
+   todo := &Tag{Name: "Todo", Virtual: true, Rule: &Rule{BodyContains: "TODO"}, Notes: sets.New[string]()}
+   if err := todo.Save(); err != nil {
+      t.Fatalf("Failed to save tag: %s", err)
+   }
+
+   t.Run("builtin", func(t *testing.T) {
+      assert.ErrorIs(t, Merge("Plain", Untagged, false), ErrVirtual)
+      assert.ErrorIs(t, Merge(Untagged, "Plain", false), ErrVirtual)
+      assert.ErrorIs(t, Rename(Untagged, "Triage", false), ErrVirtual)
+   })
+
+   t.Run("virtual", func(t *testing.T) {
+      assert.ErrorIs(t, Merge("Plain", "Todo", false), ErrVirtual)
+      assert.ErrorIs(t, Rename("Todo", "Later", false), ErrVirtual)
+   })
+
+   // Nothing should have been written by any of them
+   saved, err := note.ReadFile(filepath.Join(paths.Notes(), n.GenFileName()))
+   if assert.Nil(t, err) {
+      assert.Equal(t, sets.New("Plain"), saved.Tags)
+   }
+   tm, err := LoadAll()
+   if assert.Nil(t, err) {
+      assert.NotNil(t, tm.Get("Plain"))
+      assert.NotNil(t, tm.Get("Todo"))
+      assert.Nil(t, tm.Get("Triage"))
+      assert.Nil(t, tm.Get("Later"))
+   }
+   builtin, err := filepath.Glob(filepath.Join(paths.Tags(), Untagged + ".*"))
+   assert.Nil(t, err)
+   assert.Empty(t, builtin, "no tag file should be written for a built in tag")
+}
@@ -0,0 +1,118 @@
+package tags
+
+import (
+   "cmp"
+   "slices"
+   "strings"
+
+   "github.com/sahilm/fuzzy"
+)
+
+
+// Suggestion is a tag suggested as a completion of a partially typed tag name.
+type Suggestion struct {
+   // Tag is the suggested tag.
+   Tag *Tag
+
+   // Match is the name or alias of the tag which matched.
+   Match string
+
+   // MatchedIndexes are the byte indexes of the characters in Match which matched, for highlighting.
+   MatchedIndexes []int
+}
+
+
+// Suggest fuzzy matches partial input against the names and aliases of all the tags, returning up to limit of the best
+// matches, best first. Each tag is only suggested once, by whichever of its names matched best. Fuzzy matching only
+// finds names containing the input's characters in order, so names within a small edit distance of the input are
+// suggested after those, to catch typos such as transposed letters.
+func (m *TagMap) Suggest(input string, limit int) (suggestions []Suggestion) {
+   if input == "" {
+      return
+   }
+   var names []string
+   var tags []*Tag
+   seen := map[*Tag]bool{}
+   for _, t := range *m {
+      if seen[t] {
+         continue
+      }
+      seen[t] = true
+      for _, name := range append([]string{t.Name}, t.Aliases...) {
+         names = append(names, name)
+         tags = append(tags, t)
+      }
+   }
+
+   // The map iteration order is random, so ties in score need breaking by name for the results to be stable
+   matches := fuzzy.Find(input, names)
+   slices.SortStableFunc(matches, func(a, b fuzzy.Match) int {
+      return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Str, b.Str))
+   })
+   clear(seen)
+   for _, match := range matches {
+      t := tags[match.Index]
+      if seen[t] {
+         continue
+      }
+      seen[t] = true
+      suggestions = append(suggestions, Suggestion{Tag: t, Match: match.Str, MatchedIndexes: match.MatchedIndexes})
+      if limit > 0 && len(suggestions) >= limit {
+         return
+      }
+   }
+
+   type near struct {
+      index, distance int
+   }
+   var typos []near
+   maxDistance := max(1, len([]rune(input)) / 4)
+   for i, name := range names {
+      if d := distance(strings.ToLower(input), strings.ToLower(name)); d <= maxDistance {
+         typos = append(typos, near{i, d})
+      }
+   }
+   slices.SortStableFunc(typos, func(a, b near) int {
+      return cmp.Or(cmp.Compare(a.distance, b.distance), strings.Compare(names[a.index], names[b.index]))
+   })
+   for _, typo := range typos {
+      t := tags[typo.index]
+      if seen[t] {
+         continue
+      }
+      seen[t] = true
+      suggestions = append(suggestions, Suggestion{Tag: t, Match: names[typo.index]})
+      if limit > 0 && len(suggestions) >= limit {
+         return
+      }
+   }
+   return
+}
+
+
+// distance returns the edit distance between two strings, counting insertions, deletions, substitutions, and
+// transpositions of adjacent characters (the optimal string alignment distance).
+func distance(a, b string) int {
+   s, t := []rune(a), []rune(b)
+   d := make([][]int, len(s) + 1)
+   for i := range d {
+      d[i] = make([]int, len(t) + 1)
+      d[i][0] = i
+   }
+   for j := range d[0] {
+      d[0][j] = j
+   }
+   for i := 1; i <= len(s); i++ {
+      for j := 1; j <= len(t); j++ {
+         cost := 1
+         if s[i-1] == t[j-1] {
+            cost = 0
+         }
+         d[i][j] = min(d[i-1][j] + 1, d[i][j-1] + 1, d[i-1][j-1] + cost)
+         if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+            d[i][j] = min(d[i][j], d[i-2][j-2] + 1)
+         }
+      }
+   }
+   return d[len(s)][len(t)]
+}
@@ -0,0 +1,46 @@
+package tags
+
+import (
+   "testing"
+
+   "github.com/stretchr/testify/assert"
+)
+
+
+func Test_TagMap_Suggest(t *testing.T) {
+   tm := testTagMap(t)
+
+   names := func(s []Suggestion) (names []string) {
+      for _, sg := range s {
+         names = append(names, sg.Tag.Name)
+      }
+      return
+   }
+   assert.Equal(t, []string{"Music"}, names(tm.Suggest("musc", 0)))
+   // Aliases match too, but their tag is only suggested once
+   suggestions := tm.Suggest("tun", 0)
+   assert.Equal(t, []string{"Music", "Instrument"}, names(suggestions))
+   if assert.NotEmpty(t, suggestions) {
+      assert.Equal(t, "Music", suggestions[0].Tag.Name)
+      assert.Equal(t, "Tunes", suggestions[0].Match)
+      assert.Equal(t, []int{0, 1, 2}, suggestions[0].MatchedIndexes)
+   }
+   // Typos the fuzzy matching can't see past are still caught
+   suggestions = tm.Suggest("muisc", 0)
+   if assert.Len(t, suggestions, 1) {
+      assert.Equal(t, "Music", suggestions[0].Tag.Name)
+      assert.Empty(t, suggestions[0].MatchedIndexes)
+   }
+   assert.Len(t, tm.Suggest("i", 2), 2)
+   assert.Empty(t, tm.Suggest("", 0))
+   assert.Empty(t, tm.Suggest("xyz", 0))
+}
+
+
+func Test_distance(t *testing.T) {
+   assert.Equal(t, 0, distance("music", "music"))
+   assert.Equal(t, 1, distance("muisc", "music"))
+   assert.Equal(t, 1, distance("musc", "music"))
+   assert.Equal(t, 2, distance("banjo", "bingo"))
+   assert.Equal(t, 5, distance("", "music"))
+}
@@ -5,10 +5,12 @@ import (
    "fmt"
    "os"
    "path/filepath"
+   "slices"
 
    "github.com/omnikron13/zelkata/config"
    "github.com/omnikron13/zelkata/note"
    "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/txn"
 
    "gopkg.in/yaml.v3"
    "k8s.io/apimachinery/pkg/util/sets"
@@ -33,6 +35,10 @@ type Tag struct {
    // concepts up the tag hierarchy, or for tags automatically applied under certain conditions.
    Virtual bool
 
+   // Rule is the condition under which a virtual tag applies to notes, if it is one which is applied automatically.
+   // The Notes of such a tag are computed from the rule by TagMap.ApplyVirtual, rather than being stored.
+   Rule *Rule
+
    // Icon is a string holding a unicode sequence for an icon to be used to represent the tag.
    Icon string
 
@@ -58,6 +64,13 @@ type Tag struct {
    // Notes is a set of the UUIDs of notes that have this tag. The canonical connection between note and tag is
    // actually the note file, but it is obviously useful to be able to perform the reverse lookup.
    Notes sets.Set[string]
+
+   // builtin marks a tag which doesn't have a tag file, but exists anyway; see builtinTags.
+   builtin bool
+
+   // file is the path the tag was loaded from, if it was, so that it is saved back to the same file even if that isn't
+   // the name GenFileName would give it (e.g. a hand-written tag file).
+   file string
 }
 
 
@@ -92,6 +105,20 @@ func (t *Tag) GenFileName() (name string, err error) {
 }
 
 
+// Path returns the path of the tag's file; the file it was loaded from, or otherwise its generated name in the tags
+// directory.
+func (t *Tag) Path() (string, error) {
+   if t.file != "" {
+      return t.file, nil
+   }
+   name, err := t.GenFileName()
+   if err != nil {
+      return "", err
+   }
+   return filepath.Join(paths.Tags(), name), nil
+}
+
+
 // LoadName reads a tag file by name and returns a Tag struct.
 // This is a convenience function that calls LoadPath with the full path and normalised tag name.
 func LoadName(name string) (*Tag, error) {
@@ -119,6 +146,7 @@ func LoadPath(filePath string) (t *Tag, err error) {
    var b []byte
    if b, err = os.ReadFile(filePath); err != nil { return } else
       { err = yaml.Unmarshal(b, &t) }
+   if t != nil { t.file = filePath }
    return
 }
 
@@ -130,21 +158,23 @@ func (t Tag) MarshalYAML() (interface{}, error) {
       "notes": sets.List(t.Notes),
    }
    if t.Virtual { data["virtual"] = t.Virtual }
+   if t.Rule != nil {
+      data["rule"] = t.Rule
+      data["notes"] = []string{}
+   }
    if len(t.Aliases) > 0 { data["aliases"] = t.Aliases }
    if t.Description != "" { data["description"] = t.Description }
    if t.Icon!= "" { data["icon"] = t.Icon }
+   // Parents & relations are sorted, so that tag files don't churn on every save with random map ordering
    if len(t.Parents) > 0 {
-      parents := make([]string, 0, len(t.Parents))
-      for k := range t.Parents {
-         parents = append(parents, k)
-      }
-      data["parents"] = parents
+      data["parents"] = sets.List(t.Parents)
    }
    if len(t.Relations) > 0 {
       relations := make([]struct{Name, Description string}, 0 , len(t.Relations))
       for k, v := range t.Relations {
          relations = append(relations, struct {Name, Description string}{Name:k, Description:v})
       }
+      slices.SortFunc(relations, func(a, b struct{Name, Description string}) int { return Compare(a.Name, b.Name) })
       data["relations"] = relations
    }
    return interface{}(data), nil
@@ -159,11 +189,10 @@ func (t *Tag) normalisedName() string {
 
 // Save writes a Tag struct to a file in the tags directory.
 func (t *Tag) Save() error {
-   name, err := t.GenFileName()
+   path, err := t.Path()
    if err != nil {
       return err
    }
-   path := filepath.Join(paths.Tags(), name)
    return t.SaveAs(path)
 }
 
@@ -174,7 +203,7 @@ func (t *Tag) SaveAs(filePath string) error {
    if err != nil {
       return err
    }
-   if err = os.WriteFile(filePath, b, 0600); err == nil { return nil }
+   if err = txn.WriteFile(filePath, b, 0600); err == nil { return nil }
    return fmt.Errorf("Failure writing tag file at %s during Save()", filePath)
 }
 
@@ -197,6 +226,20 @@ func (t *Tag) UnmarshalYAML(value *yaml.Node) error {
    if _, ok := data["virtual"]; ok {
       t.Virtual = true
    }
+   if _, ok := data["rule"]; ok {
+      var r struct{ Rule *Rule }
+      if err := value.Decode(&r); err != nil {
+         return err
+      }
+      if r.Rule != nil {
+         if err := r.Rule.Validate(); err != nil {
+            return fmt.Errorf("tag %s: %w", t.Name, err)
+         }
+         // A tag with a rule is inherently virtual
+         t.Virtual = true
+         t.Rule = r.Rule
+      }
+   }
    if aliases, ok := data["aliases"]; ok {
       t.Aliases = []string{}
       for _, a := range aliases.([]any) {
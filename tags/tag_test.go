@@ -22,6 +22,7 @@ func Test_LoadPath(t *testing.T) {
          "QWERTYUIOP",
          "ASDFGHJKLZ",
       ),
+      file: path,
    }, *tag)
 }
 
@@ -2,11 +2,15 @@ package tags
 
 import (
    "errors"
+   "fmt"
    "os"
    "path/filepath"
+   "slices"
+   "strings"
 
    "github.com/omnikron13/zelkata/note"
    "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/txn"
 
    "k8s.io/apimachinery/pkg/util/sets"
 )
@@ -41,6 +45,11 @@ func LoadAll() (TagMap, error) {
          }
       }
    }
+   for _, tag := range builtinTags() {
+      if tm.Get(tag.Name) == nil {
+         _ = tm.Add(tag.Name, tag)
+      }
+   }
    return tm, nil
 }
 
@@ -63,6 +72,92 @@ func (m *TagMap) Get(name string) *Tag {
 }
 
 
+// Ancestors returns every tag above the named tag in the hierarchy; its parents, their parents, and so on, nearest
+// first. The hierarchy is a DAG in principle, but nothing stops a user making a cycle, so each tag is only visited once.
+// Returns nil if the named tag doesn't exist.
+func (m *TagMap) Ancestors(name string) []*Tag {
+   return m.walk(name, -1, func(t *Tag) []*Tag {
+      var parents []*Tag
+      for p := range t.Parents {
+         if pt := m.Get(p); pt != nil {
+            parents = append(parents, pt)
+         }
+      }
+      return parents
+   })
+}
+
+
+// Descendants returns every tag below the named tag in the hierarchy; those which have it as a parent, those which
+// have _them_ as a parent, and so on, nearest first. As with Ancestors, cycles are safely ignored.
+func (m *TagMap) Descendants(name string) []*Tag {
+   return m.walk(name, -1, m.childrenFunc())
+}
+
+
+// NotesUnder returns the IDs of the notes with the named tag, or any of its descendants up to depth levels below it.
+// A depth of 0 is just the tag itself, and a negative depth is unlimited.
+func (m *TagMap) NotesUnder(name string, depth int) sets.Set[string] {
+   notes := sets.New[string]()
+   tag := m.Get(name)
+   if tag == nil {
+      return notes
+   }
+   notes = notes.Union(tag.Notes)
+   for _, t := range m.walk(name, depth, m.childrenFunc()) {
+      notes = notes.Union(t.Notes)
+   }
+   return notes
+}
+
+
+// childrenFunc returns a function giving the direct children of a tag, indexing the whole TagMap up front so that
+// walking down the hierarchy doesn't have to scan every tag at every step.
+func (m *TagMap) childrenFunc() func(*Tag) []*Tag {
+   children := map[*Tag][]*Tag{}
+   for name, t := range *m {
+      if name != normaliseName(t.Name) {
+         continue
+      }
+      for p := range t.Parents {
+         if pt := m.Get(p); pt != nil {
+            children[pt] = append(children[pt], t)
+         }
+      }
+   }
+   return func(t *Tag) []*Tag { return children[t] }
+}
+
+
+// walk does a breadth first traversal of the tag graph from the named tag, following the edges given by next, up to
+// depth levels (or unlimited if negative). The starting tag itself isn't included in the results.
+func (m *TagMap) walk(name string, depth int, next func(*Tag) []*Tag) (found []*Tag) {
+   start := m.Get(name)
+   if start == nil {
+      return nil
+   }
+   seen := sets.New(start)
+   level := []*Tag{start}
+   for d := 0; len(level) > 0 && (depth < 0 || d < depth); d++ {
+      var nextLevel []*Tag
+      for _, t := range level {
+         for _, n := range next(t) {
+            if seen.Has(n) {
+               continue
+            }
+            seen.Insert(n)
+            nextLevel = append(nextLevel, n)
+         }
+      }
+      // Keep the order within each level stable, as map iteration order certainly isn't
+      slices.SortFunc(nextLevel, func(a, b *Tag) int { return strings.Compare(a.Name, b.Name) })
+      found = append(found, nextLevel...)
+      level = nextLevel
+   }
+   return
+}
+
+
 // Reindex clears the Notes field of all tags in the TagMap, then repopulates them by scanning the notes directory.
 func (m *TagMap) Reindex() error {
    // Clear existing note references
@@ -74,14 +169,14 @@ func (m *TagMap) Reindex() error {
    }
 
    // Read notes and add their IDs to the appropriate tags, creating new tags as necessary
-   files, err := os.ReadDir(paths.Notes())
+   files, err := note.Files()
    if err != nil {
       return err
    }
    for _, file := range files {
-      note, err := note.ReadFile(filepath.Join(paths.Notes(), file.Name()))
+      note, err := note.ReadFile(file)
       if  err != nil {
-         return err
+         return fmt.Errorf("error reading note %s: %w", file, err)
       }
       for t := range note.Tags {
          tag := m.Get(t)
@@ -96,10 +191,98 @@ func (m *TagMap) Reindex() error {
 }
 
 
-// Save writes all (non-alias) Tag structs in the TagMap to files in the tags directory.
+// Retag moves a note between tags by name; removing its ID from the tags in before which aren't also in after, and
+// adding it to those in after, creating new tags as necessary. Names are resolved through the TagMap, so swapping a tag
+// for one of its own aliases is a no-op. The tags which were actually changed are returned so they can be saved.
+func (m *TagMap) Retag(noteID string, before, after sets.Set[string]) (changed []*Tag) {
+   keep := sets.New[*Tag]()
+   for name := range after {
+      tag := m.Get(name)
+      if tag == nil {
+         tag = &Tag{Name: name, Notes: sets.New[string]()}
+         _ = m.Add(name, tag)
+      }
+      if tag.Notes == nil {
+         tag.Notes = sets.New[string]()
+      }
+      keep.Insert(tag)
+      if !tag.Notes.Has(noteID) {
+         tag.Notes.Insert(noteID)
+         changed = append(changed, tag)
+      }
+   }
+   for name := range before {
+      tag := m.Get(name)
+      if tag == nil || keep.Has(tag) || !tag.Notes.Has(noteID) {
+         continue
+      }
+      tag.Notes.Delete(noteID)
+      changed = append(changed, tag)
+   }
+   return
+}
+
+
+// RemoveNote removes a note ID from every tag in the TagMap, regardless of whether the note itself lists the tag,
+// returning the tags which actually changed.
+func (m *TagMap) RemoveNote(noteID string) (changed []*Tag) {
+   for name, tag := range *m {
+      if name != normaliseName(tag.Name) || !tag.Notes.Has(noteID) {
+         continue
+      }
+      tag.Notes.Delete(noteID)
+      changed = append(changed, tag)
+   }
+   return
+}
+
+
+// Retag loads all the tags, moves a note between them as TagMap.Retag does, and saves those that changed. Adding the
+// note to a virtual tag is refused, though a note keeping a virtual tag it somehow already had is let be.
+func Retag(noteID string, before, after sets.Set[string]) error {
+   tx := txn.New()
+   if err := RetagTx(tx, noteID, before, after); err != nil {
+      return err
+   }
+   return tx.Commit()
+}
+
+
+// RetagTx is Retag, but queues writing the changed tags as part of a Txn rather than writing them immediately, so that
+// they can be saved together with the note itself.
+func RetagTx(tx *txn.Txn, noteID string, before, after sets.Set[string]) error {
+   tm, err := LoadAll()
+   if err != nil {
+      return err
+   }
+   if err := tm.CheckAssignable(after.Difference(before)); err != nil {
+      return err
+   }
+   return writeTags(tx, tm.Retag(noteID, before, after)...)
+}
+
+
+// RemoveNoteTx loads all the tags and removes a note ID from every one of them as TagMap.RemoveNote does, queueing
+// writing those that changed as part of a Txn. The names of the tags which listed the note are returned, sorted.
+func RemoveNoteTx(tx *txn.Txn, noteID string) ([]string, error) {
+   tm, err := LoadAll()
+   if err != nil {
+      return nil, err
+   }
+   changed := tm.RemoveNote(noteID)
+   names := make([]string, 0, len(changed))
+   for _, t := range changed {
+      names = append(names, t.Name)
+   }
+   slices.Sort(names)
+   return names, writeTags(tx, changed...)
+}
+
+
+// Save writes all (non-alias) Tag structs in the TagMap to files in the tags directory, other than built in tags.
 func (m *TagMap) Save() error {
    for name, tag := range *m {
-      if name != normaliseName(tag.Name) {
+      if name != normaliseName(tag.Name) || tag.builtin {
          continue
       }
       if err := tag.Save(); err != nil {
@@ -4,6 +4,7 @@ import (
    "testing"
 
    "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
 )
 
 
@@ -26,3 +27,67 @@ func Test_TagMap_Get(t *testing.T) {
    assert.Nil(t, tags.Get("Non-existent Tag"))
 }
 
+
+func Test_TagMap_Retag(t *testing.T) {
+   music := &Tag{Name: "Music", Aliases: []string{"Tunes"}, Notes: sets.New("A", "B")}
+   draft := &Tag{Name: "Draft", Notes: sets.New("A")}
+   tags := TagMap{}
+   for name, tag := range map[string]*Tag{"Music": music, "Tunes": music, "Draft": draft} {
+      if err := tags.Add(name, tag); err != nil {
+         t.Skipf("Error adding tag to TagMap: %v", err)
+      }
+   }
+
+   changed := tags.Retag("A", sets.New("Music", "Draft"), sets.New("Tunes", "Guitar"))
+   assert.Equal(t, sets.New("A", "B"), music.Notes)
+   assert.Equal(t, sets.New[string](), draft.Notes)
+   guitar := tags.Get("Guitar")
+   if assert.NotNil(t, guitar) {
+      assert.Equal(t, sets.New("A"), guitar.Notes)
+   }
+   assert.ElementsMatch(t, []*Tag{draft, guitar}, changed)
+}
+
+func Test_TagMap_RemoveNote(t *testing.T) {
+   music := &Tag{Name: "Music", Aliases: []string{"Tunes"}, Notes: sets.New("A", "B")}
+   draft := &Tag{Name: "Draft", Notes: sets.New("B")}
+   tags := TagMap{}
+   for name, tag := range map[string]*Tag{"Music": music, "Tunes": music, "Draft": draft} {
+      if err := tags.Add(name, tag); err != nil {
+         t.Skipf("Error adding tag to TagMap: %v", err)
+      }
+   }
+
+   changed := tags.RemoveNote("A")
+   assert.Equal(t, []*Tag{music}, changed)
+   assert.Equal(t, sets.New("B"), music.Notes)
+   assert.Equal(t, sets.New("B"), draft.Notes)
+}
+
+func Test_TagMap_hierarchy(t *testing.T) {
+   maths := &Tag{Name: "Mathematics", Notes: sets.New("M")}
+   algebra := &Tag{Name: "Algebra", Parents: sets.New("Mathematics"), Notes: sets.New("A")}
+   geometry := &Tag{Name: "Geometry", Aliases: []string{"Geo"}, Parents: sets.New("Mathematics"), Notes: sets.New("G")}
+   linear := &Tag{Name: "Linear Algebra", Parents: sets.New("Algebra", "Geo"), Notes: sets.New("L")}
+   // A deliberate cycle, which the traversal must survive
+   music := &Tag{Name: "Music", Parents: sets.New("Mathematics"), Notes: sets.New("X")}
+   maths.Parents = sets.New("Music")
+   tags := TagMap{}
+   for _, tag := range []*Tag{maths, algebra, geometry, linear, music} {
+      for _, name := range append([]string{tag.Name}, tag.Aliases...) {
+         if err := tags.Add(name, tag); err != nil {
+            t.Skipf("Error adding tag to TagMap: %v", err)
+         }
+      }
+   }
+
+   assert.Equal(t, []*Tag{algebra, geometry, maths, music}, tags.Ancestors("Linear Algebra"))
+   assert.Equal(t, []*Tag{algebra, geometry, music, linear}, tags.Descendants("Mathematics"))
+   assert.Equal(t, []*Tag{maths, algebra, geometry, linear}, tags.Descendants("Music"))
+   assert.Nil(t, tags.Descendants("Nonexistent"))
+
+   assert.Equal(t, sets.New("M"), tags.NotesUnder("Mathematics", 0))
+   assert.Equal(t, sets.New("M", "A", "G", "X"), tags.NotesUnder("Mathematics", 1))
+   assert.Equal(t, sets.New("M", "A", "G", "X", "L"), tags.NotesUnder("Mathematics", -1))
+   assert.Equal(t, sets.New[string](), tags.NotesUnder("Nonexistent", -1))
+}
@@ -0,0 +1,212 @@
+package tags
+
+import (
+   "errors"
+   "fmt"
+   "slices"
+   "strconv"
+   "strings"
+   "time"
+
+   "github.com/omnikron13/zelkata/note"
+
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ErrVirtual is returned when a note is given a virtual tag directly.
+var ErrVirtual = errors.New("virtual tags cannot be assigned to notes directly")
+
+// Untagged is the name of the built in virtual tag applied to notes without any tags.
+const Untagged = "untagged"
+
+
+// builtinTags returns the virtual tags which exist whether or not there is a tag file for them. Writing a tag file for
+// one of them (e.g. by giving it a description) replaces the built in version.
+func builtinTags() []*Tag {
+   return []*Tag{
+      {
+         Name: Untagged,
+         Description: "Notes without any tags, which are of little use until they are linked into the rest.",
+         Virtual: true,
+         Rule: &Rule{Untagged: true},
+         Notes: sets.New[string](),
+         builtin: true,
+      },
+   }
+}
+
+
+// Rule is the condition under which a virtual tag applies to a note. Every condition which is set must hold, so that,
+// for example, a rule with both Format and BodyContains only matches notes in that format which contain that text.
+// Any and Not allow for the conditions to be combined in other ways.
+//
+//    rule:
+//       created-within: 7d
+//       any:
+//          - body-contains: TODO
+//          - tags: [Project, Active]
+type Rule struct {
+   // CreatedWithin matches notes created within the given length of time before now, as either a Go duration (e.g.
+   // "36h") or a whole number of days or weeks (e.g. "7d", "2w").
+   CreatedWithin string `yaml:"created-within,omitempty"`
+
+   // Untagged matches notes which have no tags at all.
+   Untagged bool `yaml:"untagged,omitempty"`
+
+   // BodyContains matches notes whose body contains the given text, ignoring case.
+   BodyContains string `yaml:"body-contains,omitempty"`
+
+   // Format matches notes with the given Format, ignoring case.
+   Format string `yaml:"format,omitempty"`
+
+   // Tags matches notes which have every one of the given tags, or their aliases.
+   Tags []string `yaml:"tags,omitempty"`
+
+   // Any matches notes which match at least one of the given rules.
+   Any []Rule `yaml:"any,omitempty"`
+
+   // Not matches notes which do not match the given rule.
+   Not *Rule `yaml:"not,omitempty"`
+}
+
+
+// Validate checks that the rule, and every rule within it, can be evaluated.
+func (r *Rule) Validate() error {
+   if r.CreatedWithin != "" {
+      if _, err := parseWithin(r.CreatedWithin); err != nil {
+         return err
+      }
+   }
+   for i := range r.Any {
+      if err := r.Any[i].Validate(); err != nil {
+         return err
+      }
+   }
+   if r.Not != nil {
+      return r.Not.Validate()
+   }
+   return nil
+}
+
+
+// Match reports whether a note satisfies the rule, as of the given time. The TagMap is used to resolve the names of
+// tags to match, so that aliases work as they do anywhere else.
+func (r *Rule) Match(m TagMap, n *note.Note, now time.Time) bool {
+   if r.CreatedWithin != "" {
+      d, err := parseWithin(r.CreatedWithin)
+      if err != nil || n.Created.Before(now.Add(-d)) {
+         return false
+      }
+   }
+   if r.Untagged && len(n.Tags) > 0 {
+      return false
+   }
+   if r.BodyContains != "" && !strings.Contains(strings.ToLower(n.Body), strings.ToLower(r.BodyContains)) {
+      return false
+   }
+   if r.Format != "" && (n.Format == nil || !strings.EqualFold(*n.Format, r.Format)) {
+      return false
+   }
+   for _, want := range r.Tags {
+      if !noteHasTag(m, n, want) {
+         return false
+      }
+   }
+   if len(r.Any) > 0 {
+      matched := false
+      for i := range r.Any {
+         if matched = r.Any[i].Match(m, n, now); matched {
+            break
+         }
+      }
+      if !matched {
+         return false
+      }
+   }
+   if r.Not != nil && r.Not.Match(m, n, now) {
+      return false
+   }
+   return true
+}
+
+
+// noteHasTag reports whether a note lists the named tag, resolving both through the TagMap.
+func noteHasTag(m TagMap, n *note.Note, name string) bool {
+   want := m.Get(name)
+   for t := range n.Tags {
+      if strings.EqualFold(t, name) || (want != nil && m.Get(t) == want) {
+         return true
+      }
+   }
+   return false
+}
+
+
+// parseWithin parses the length of time for Rule.CreatedWithin.
+func parseWithin(s string) (time.Duration, error) {
+   for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+      if num, ok := strings.CutSuffix(s, suffix); ok {
+         n, err := strconv.Atoi(num)
+         if err != nil || n < 0 {
+            return 0, fmt.Errorf("invalid length of time: %q", s)
+         }
+         return time.Duration(n) * unit, nil
+      }
+   }
+   d, err := time.ParseDuration(s)
+   if err != nil {
+      return 0, fmt.Errorf("invalid length of time: %q", s)
+   }
+   return d, nil
+}
+
+
+// ApplyVirtual evaluates the rules of all the virtual tags against the given notes, replacing the Notes of each of
+// those tags with the notes its rule matches. The tags are not saved; virtual tags are evaluated afresh each time they
+// are needed, and their Notes are never written to their tag files.
+func (m *TagMap) ApplyVirtual(notes []note.Note) {
+   now := time.Now()
+   for name, t := range *m {
+      if t.Rule == nil || name != normaliseName(t.Name) {
+         continue
+      }
+      t.Notes = sets.New[string]()
+      for i := range notes {
+         if t.Rule.Match(*m, &notes[i], now) {
+            t.Notes.Insert(notes[i].ID)
+         }
+      }
+   }
+}
+
+
+// VirtualOf returns the names of the virtual tags which apply to the note with the given ID, sorted; ApplyVirtual has to
+// have been called first for them to be known.
+func (m *TagMap) VirtualOf(id string) []string {
+   var names []string
+   for name, t := range *m {
+      if t.Virtual && name == normaliseName(t.Name) && t.Notes.Has(id) {
+         names = append(names, t.Name)
+      }
+   }
+   slices.Sort(names)
+   return names
+}
+
+
+// NoteTags returns the names of all of the tags of a note, sorted; those it has itself, along with the virtual tags
+// which apply to it as given by VirtualOf. This is what the tags of a note are listed as wherever notes are shown.
+func (m *TagMap) NoteTags(n *note.Note) []string {
+   return sets.List(n.Tags.Clone().Insert(m.VirtualOf(n.ID)...))
+}
+
+
+// CheckAssignable returns an error wrapping ErrVirtual if any of the named tags is virtual, so can't be given to a note.
+func (m *TagMap) CheckAssignable(names sets.Set[string]) error {
+   for _, name := range sets.List(names) {
+      if t := m.Get(name); t != nil && t.Virtual {
+         return fmt.Errorf("%w: %s", ErrVirtual, t.Name)
+      }
+   }
+   return nil
+}
@@ -0,0 +1,102 @@
+package tags
+
+import (
+   "testing"
+   "time"
+
+   "github.com/omnikron13/zelkata/note"
+
+   "github.com/stretchr/testify/assert"
+   "gopkg.in/yaml.v3"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_Rule_Match(t *testing.T) {
+   tm := testTagMap(t)
+   now := time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC)
+   asciidoc := "AsciiDoc"
+   recent := note.Note{
+      Meta: note.Meta{ID: "A", Created: now.Add(-48 * time.Hour), Tags: sets.New("Tunes", "Banjo"), Format: &asciidoc},
+      Body: "Restring the banjo. TODO: buy strings",
+   }
+   old := note.Note{Meta: note.Meta{ID: "B", Created: now.Add(-30 * 24 * time.Hour)}, Body: "Nothing to do."}
+
+   tests := []struct {
+      name string
+      rule Rule
+      matches []string
+   }{
+      {"empty", Rule{}, []string{"A", "B"}},
+      {"created within", Rule{CreatedWithin: "7d"}, []string{"A"}},
+      {"untagged", Rule{Untagged: true}, []string{"B"}},
+      {"body contains", Rule{BodyContains: "todo"}, []string{"A"}},
+      {"format", Rule{Format: "asciidoc"}, []string{"A"}},
+      {"tags through aliases", Rule{Tags: []string{"Music", "banjo"}}, []string{"A"}},
+      {"tags missing one", Rule{Tags: []string{"Music", "Guitar"}}, nil},
+      {"all conditions", Rule{CreatedWithin: "1w", BodyContains: "TODO", Tags: []string{"Banjo"}}, []string{"A"}},
+      {"any", Rule{Any: []Rule{{Untagged: true}, {Format: "asciidoc"}}}, []string{"A", "B"}},
+      {"not", Rule{Not: &Rule{CreatedWithin: "72h"}}, []string{"B"}},
+   }
+   for _, tt := range tests {
+      t.Run(tt.name, func(t *testing.T) {
+         var matches []string
+         for _, n := range []note.Note{recent, old} {
+            if tt.rule.Match(tm, &n, now) {
+               matches = append(matches, n.ID)
+            }
+         }
+         assert.Equal(t, tt.matches, matches)
+      })
+   }
+}
+
+
+func Test_parseWithin(t *testing.T) {
+   for s, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "2w": 14 * 24 * time.Hour, "90m": 90 * time.Minute} {
+      d, err := parseWithin(s)
+      assert.Nil(t, err)
+      assert.Equal(t, want, d)
+   }
+   for _, s := range []string{"", "d", "-1d", "soon"} {
+      _, err := parseWithin(s)
+      assert.NotNil(t, err, s)
+   }
+}
+
+
+func Test_TagMap_ApplyVirtual(t *testing.T) {
+   tm := testTagMap(t)
+   todo := &Tag{Name: "Todo", Virtual: true, Rule: &Rule{BodyContains: "TODO"}, Notes: sets.New("STALE")}
+   if err := tm.Add(todo.Name, todo); err != nil {
+      t.Skipf("Error adding tag to TagMap: %v", err)
+   }
+   tm.ApplyVirtual([]note.Note{
+      {Meta: note.Meta{ID: "A"}, Body: "TODO: something"},
+      {Meta: note.Meta{ID: "B"}, Body: "Done."},
+   })
+   assert.Equal(t, sets.New("A"), todo.Notes)
+   assert.Contains(t, tm.VirtualOf("A"), "Todo")
+   assert.NotContains(t, tm.VirtualOf("B"), "Todo")
+   a := note.Note{Meta: note.Meta{ID: "A", Tags: sets.New("Banjo")}}
+   assert.Subset(t, tm.NoteTags(&a), []string{"Banjo", "Todo"})
+
+   assert.ErrorIs(t, tm.CheckAssignable(sets.New("Banjo", "Todo")), ErrVirtual)
+   assert.Nil(t, tm.CheckAssignable(sets.New("Banjo", "New")))
+}
+
+
+func Test_Tag_Rule_YAML(t *testing.T) {
+   tag := Tag{Name: "Recent", Virtual: true, Rule: &Rule{CreatedWithin: "7d", Not: &Rule{Tags: []string{"Archive"}}}, Notes: sets.New("A")}
+   data, err := yaml.Marshal(tag)
+   assert.Nil(t, err)
+   // The notes a rule matches are never stored
+   assert.Equal(t, "name: Recent\nnotes: []\nrule:\n    created-within: 7d\n    not:\n        tags:\n            - Archive\nvirtual: true\n", string(data))
+
+   loaded := Tag{}
+   assert.Nil(t, yaml.Unmarshal(data, &loaded))
+   tag.Notes = sets.New[string]()
+   assert.Equal(t, tag, loaded)
+
+   assert.NotNil(t, yaml.Unmarshal([]byte("name: Bad\nnotes: []\nrule:\n    created-within: soon\n"), &loaded))
+}
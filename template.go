@@ -0,0 +1,27 @@
+package main
+
+import (
+   "context"
+   "fmt"
+
+   "github.com/omnikron13/zelkata/templates"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// templateLsCmd lists the available note templates; their names, where they come from, and their descriptions.
+func templateLsCmd(ctx context.Context, cmd *cli.Command) error {
+   list, err := templates.List()
+   if err != nil {
+      return err
+   }
+   for _, t := range list {
+      source := "user"
+      if t.Builtin {
+         source = "builtin"
+      }
+      fmt.Printf("%s\t%s\t%s\n", t.Name, source, t.Description)
+   }
+   return nil
+}
@@ -0,0 +1,219 @@
+// Package templates provides the note templates used to start new notes from something other than an empty file. A
+// template is a file much like a note; YAML front matter holding the defaults for the new note (tags, title, format)
+// and a description of the template, followed by a skeleton body. The title and body can contain text/template
+// placeholders, expanded when the note is created:
+//
+//    {{date}}             the date the note was created
+//    {{time}}             the time the note was created
+//    {{id}}               the ID of the new note
+//    {{prompt "Author"}}  asks the user for a value, only once for each distinct prompt
+//
+// A few templates are built in, but templates in the user's templates directory are preferred over them, so they can
+// be overridden as well as added to.
+package templates
+
+import (
+   "bytes"
+   "embed"
+   "errors"
+   "fmt"
+   "io/fs"
+   "os"
+   "path/filepath"
+   "slices"
+   "strings"
+   "text/template"
+   "time"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+
+   "gopkg.in/yaml.v3"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+//go:embed builtin/*.md
+var builtin embed.FS
+
+
+// Template is a template for new notes.
+type Template struct {
+   // Name is the name the template is selected by; its filename without the extension.
+   Name string `yaml:"-"`
+
+   // Description is a short summary of what the template is for, shown when listing templates.
+   Description string `yaml:"description,omitempty"`
+
+   // Tags are the tags new notes are given to begin with.
+   Tags []string `yaml:"tags,omitempty"`
+
+   // Title is the explicit title new notes are given, if any. It may contain placeholders.
+   Title string `yaml:"title,omitempty"`
+
+   // Format is the format new notes are given, if any.
+   Format string `yaml:"format,omitempty"`
+
+   // Body is the skeleton body of new notes. It may contain placeholders.
+   Body string `yaml:"-"`
+
+   // Builtin reports whether the template is one of the built in templates, rather than a user template.
+   Builtin bool `yaml:"-"`
+}
+
+
+// PromptFunc asks the user for the value of a {{prompt}} placeholder.
+type PromptFunc func(label string) (string, error)
+
+
+// Parse reads a template from the contents of a template file. The front matter is optional, but if present it is
+// delimited just as a note's is.
+func Parse(name string, b []byte) (t Template, err error) {
+   meta, body, err := note.SplitFrontMatter(b)
+   if errors.Is(err, note.ErrNoFrontMatter) {
+      meta, body, err = nil, b, nil
+   }
+   if err != nil {
+      return t, fmt.Errorf("template %s: %w", name, err)
+   }
+   if err = yaml.Unmarshal(meta, &t); err != nil {
+      return t, fmt.Errorf("template %s: %w", name, err)
+   }
+   t.Name = name
+   t.Body = string(body)
+   return
+}
+
+
+// Load returns the named template, preferring a user template to a built in one.
+func Load(name string) (Template, error) {
+   all, err := List()
+   if err != nil {
+      return Template{}, err
+   }
+   i := slices.IndexFunc(all, func(t Template) bool { return t.Name == name })
+   if i < 0 {
+      return Template{}, fmt.Errorf("no such template: %s", name)
+   }
+   return all[i], nil
+}
+
+
+// List returns all of the available templates sorted by name, with user templates replacing any built in templates
+// of the same name.
+func List() ([]Template, error) {
+   found := map[string]Template{}
+
+   entries, err := fs.ReadDir(builtin, "builtin")
+   if err != nil {
+      return nil, err
+   }
+   for _, e := range entries {
+      b, err := builtin.ReadFile("builtin/" + e.Name())
+      if err != nil {
+         return nil, err
+      }
+      t, err := Parse(templateName(e.Name()), b)
+      if err != nil {
+         return nil, err
+      }
+      t.Builtin = true
+      found[t.Name] = t
+   }
+
+   entries, err = os.ReadDir(paths.Templates())
+   if err != nil && !errors.Is(err, os.ErrNotExist) {
+      return nil, err
+   }
+   for _, e := range entries {
+      if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+         continue
+      }
+      b, err := os.ReadFile(filepath.Join(paths.Templates(), e.Name()))
+      if err != nil {
+         return nil, err
+      }
+      t, err := Parse(templateName(e.Name()), b)
+      if err != nil {
+         return nil, err
+      }
+      found[t.Name] = t
+   }
+
+   list := make([]Template, 0, len(found))
+   for _, t := range found {
+      list = append(list, t)
+   }
+   slices.SortFunc(list, func(a, b Template) int { return strings.Compare(a.Name, b.Name) })
+   return list, nil
+}
+
+
+// templateName returns the name of a template from its filename.
+func templateName(filename string) string {
+   return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
+
+
+// Note creates a new note from the template, with its placeholders expanded. The note's Meta is created first, so
+// that its ID and creation time can be used in the placeholders.
+func (t *Template) Note(prompt PromptFunc) (n note.Note, err error) {
+   n = note.New("")
+   funcs := placeholders(&n, prompt)
+
+   if n.Body, err = expand(t.Name, t.Body, funcs); err != nil {
+      return
+   }
+   if t.Title != "" {
+      title, err := expand(t.Name + " title", t.Title, funcs)
+      if err != nil {
+         return n, err
+      }
+      n.Title = &title
+   }
+   if t.Format != "" {
+      format := t.Format
+      n.Format = &format
+   }
+   n.Tags = sets.New(t.Tags...)
+   return
+}
+
+
+// placeholders returns the functions available to templates for a note. Answers to prompts are remembered, so the
+// same prompt can be used more than once in a template without the user being asked again.
+func placeholders(n *note.Note, prompt PromptFunc) template.FuncMap {
+   answers := map[string]string{}
+   return template.FuncMap{
+      "date": func() string { return n.Created.Local().Format(time.DateOnly) },
+      "time": func() string { return n.Created.Local().Format("15:04") },
+      "id": func() string { return n.ID },
+      "prompt": func(label string) (string, error) {
+         if a, ok := answers[label]; ok {
+            return a, nil
+         }
+         if prompt == nil {
+            return "", fmt.Errorf("cannot prompt for %q", label)
+         }
+         a, err := prompt(label)
+         if err != nil {
+            return "", err
+         }
+         answers[label] = a
+         return a, nil
+      },
+   }
+}
+
+
+// expand executes a single piece of template text.
+func expand(name, text string, funcs template.FuncMap) (string, error) {
+   tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+   if err != nil {
+      return "", err
+   }
+   var b bytes.Buffer
+   if err := tmpl.Execute(&b, nil); err != nil {
+      return "", err
+   }
+   return b.String(), nil
+}
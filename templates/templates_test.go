@@ -0,0 +1,108 @@
+package templates
+
+import (
+   "os"
+   "path/filepath"
+   "testing"
+   "time"
+
+   "github.com/omnikron13/zelkata/internal/testenv"
+   "github.com/omnikron13/zelkata/paths"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_Parse(t *testing.T) {
+   testenv.Setup(t)
+   t.Run("front matter", func(t *testing.T) {
+      tmpl, err := Parse("test", []byte("---\ndescription: A test\ntags: [Foo, Bar]\ntitle: Hi\n...\n\nBody\n"))
+      assert.Nil(t, err)
+      assert.Equal(t, Template{
+         Name: "test",
+         Description: "A test",
+         Tags: []string{"Foo", "Bar"},
+         Title: "Hi",
+         Body: "Body\n",
+      }, tmpl)
+   })
+
+   t.Run("dashes and empty front matter", func(t *testing.T) {
+      tmpl, err := Parse("test", []byte("---\n---\nBody\n---\n"))
+      assert.Nil(t, err)
+      assert.Equal(t, Template{Name: "test", Body: "Body\n---\n"}, tmpl)
+   })
+
+   t.Run("crlf", func(t *testing.T) {
+      tmpl, err := Parse("test", []byte("---\r\ndescription: A test\r\ntags: [Foo]\r\n...\r\n\r\nBody\r\n"))
+      assert.Nil(t, err)
+      assert.Equal(t, Template{Name: "test", Description: "A test", Tags: []string{"Foo"}, Body: "Body\r\n"}, tmpl)
+   })
+
+   t.Run("no front matter", func(t *testing.T) {
+      tmpl, err := Parse("test", []byte("# Just a body\n"))
+      assert.Nil(t, err)
+      assert.Equal(t, Template{Name: "test", Body: "# Just a body\n"}, tmpl)
+   })
+
+   t.Run("unterminated", func(t *testing.T) {
+      _, err := Parse("test", []byte("---\ntags: [Foo]\n"))
+      assert.NotNil(t, err)
+   })
+}
+
+
+func Test_Note(t *testing.T) {
+   testenv.Setup(t)
+   tmpl := Template{
+      Name: "test",
+      Tags: []string{"Book"},
+      Title: `{{prompt "Title"}}`,
+      Format: "AsciiDoc",
+      Body: `{{prompt "Title"}} by {{prompt "Author"}}, {{id}} {{date}}`,
+   }
+   asked := []string{}
+   n, err := tmpl.Note(func(label string) (string, error) {
+      asked = append(asked, label)
+      return "answer to " + label, nil
+   })
+   assert.Nil(t, err)
+   assert.Equal(t, []string{"Title", "Author"}, asked)
+   assert.Equal(t, "answer to Title", *n.Title)
+   assert.Equal(t, "AsciiDoc", *n.Format)
+   assert.Equal(t, sets.New("Book"), n.Tags)
+   assert.Equal(t, "answer to Title by answer to Author, " + n.ID + " " + n.Created.Local().Format(time.DateOnly), n.Body)
+
+   _, err = tmpl.Note(nil)
+   assert.NotNil(t, err)
+}
+
+
+func Test_List(t *testing.T) {
+   testenv.Setup(t)
+   err := os.WriteFile(filepath.Join(paths.Templates(), "idea.md"), []byte("---\ndescription: Mine\n...\n\n"), 0600)
+   if err != nil {
+      t.Fatalf("Failed to write template: %s", err)
+   }
+   list, err := List()
+   assert.Nil(t, err)
+   names := []string{}
+   for _, tmpl := range list {
+      names = append(names, tmpl.Name)
+      // Every built in template must at least expand
+      if tmpl.Builtin {
+         _, err := tmpl.Note(func(string) (string, error) { return "x", nil })
+         assert.Nil(t, err, tmpl.Name)
+      }
+   }
+   assert.Equal(t, []string{"book", "bug", "idea", "meeting"}, names)
+
+   idea, err := Load("idea")
+   assert.Nil(t, err)
+   assert.False(t, idea.Builtin)
+   assert.Equal(t, "Mine", idea.Description)
+
+   _, err = Load("nonexistent")
+   assert.NotNil(t, err)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+   "context"
+   "errors"
+   "fmt"
+   "time"
+
+   "github.com/omnikron13/zelkata/trash"
+
+   "github.com/urfave/cli/v3"
+)
+
+
+// rmCmd moves a note into the trash, rather than deleting it outright.
+func rmCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() < 1 {
+      return errors.New("rm requires at least one note ID")
+   }
+   for _, id := range cmd.Args().Slice() {
+      e, err := trash.Trash(id)
+      if err != nil {
+         return err
+      }
+      fmt.Printf("moved %s to trash\n", e.ID)
+   }
+   return nil
+}
+
+
+// trashListCmd prints a line for each trashed note; ID, when it was trashed, and its original filename.
+func trashListCmd(ctx context.Context, cmd *cli.Command) error {
+   entries, err := trash.List()
+   if err != nil {
+      return err
+   }
+   for _, e := range entries {
+      fmt.Printf("%s\t%s\t%s\n", e.ID, e.Deleted.Local().Format(time.DateTime), e.File)
+   }
+   return nil
+}
+
+
+// trashRestoreCmd puts trashed notes back where they came from.
+func trashRestoreCmd(ctx context.Context, cmd *cli.Command) error {
+   if cmd.NArg() < 1 {
+      return errors.New("restore requires at least one note ID")
+   }
+   for _, id := range cmd.Args().Slice() {
+      e, err := trash.Restore(id)
+      if err != nil {
+         return err
+      }
+      fmt.Printf("restored %s\n", e.ID)
+   }
+   return nil
+}
+
+
+// trashEmptyCmd permanently deletes trashed notes, optionally only those which have been in the trash for a while.
+func trashEmptyCmd(ctx context.Context, cmd *cli.Command) error {
+   removed, err := trash.Empty(cmd.Duration("older-than"))
+   for _, e := range removed {
+      fmt.Printf("deleted %s\n", e.ID)
+   }
+   return err
+}
@@ -0,0 +1,188 @@
+// Package trash provides a recoverable alternative to deleting notes outright. Trashed note files are moved, untouched,
+// into a trash directory alongside a small YAML record of where they came from and which tags referenced them, so that
+// they can be put back exactly as they were until the trash is emptied.
+package trash
+
+import (
+   "fmt"
+   "os"
+   "path/filepath"
+   "slices"
+   "strings"
+   "time"
+
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/tags"
+   "github.com/omnikron13/zelkata/txn"
+
+   "gopkg.in/yaml.v3"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+// recordExt is the extension of the records kept alongside trashed notes.
+const recordExt = ".trash.yaml"
+
+
+// Entry is the record of a single trashed note.
+type Entry struct {
+   // ID is the ID of the trashed note.
+   ID string `yaml:"id"`
+
+   // File is the original filename of the note, which is also its name inside the trash directory.
+   File string `yaml:"file"`
+
+   // Deleted is when the note was moved to the trash.
+   Deleted time.Time `yaml:"deleted"`
+
+   // Tags are the names of the tags which referenced the note at the time it was trashed.
+   Tags []string `yaml:"tags,omitempty"`
+}
+
+
+// Trash moves the note with the given (full or partial) ID into the trash, removing its ID from every tag. The record,
+// the move and the tags are all committed together, so a note is never left in the trash without its record, nor with
+// tags still listing it.
+func Trash(id string) (*Entry, error) {
+   path, err := note.FindByID(id)
+   if err != nil {
+      return nil, err
+   }
+   e := &Entry{
+      ID: note.IDFromFileName(path),
+      File: filepath.Base(path),
+      Deleted: time.Now().UTC(),
+   }
+
+   tx := txn.New()
+   if e.Tags, err = tags.RemoveNoteTx(tx, e.ID); err != nil {
+      return nil, err
+   }
+   record, err := yaml.Marshal(e)
+   if err != nil {
+      return nil, err
+   }
+   tx.Write(e.recordPath(), record, 0600)
+   if err := move(tx, path, filepath.Join(paths.Trash(), e.File)); err != nil {
+      return nil, err
+   }
+   return e, tx.Commit()
+}
+
+
+// List returns the records of all the notes currently in the trash, oldest first.
+func List() (entries []Entry, err error) {
+   files, err := filepath.Glob(filepath.Join(paths.Trash(), "*" + recordExt))
+   if err != nil {
+      return
+   }
+   for _, f := range files {
+      var b []byte
+      if b, err = os.ReadFile(f); err != nil {
+         return
+      }
+      var e Entry
+      if err = yaml.Unmarshal(b, &e); err != nil {
+         return nil, fmt.Errorf("error reading trash record %s: %w", f, err)
+      }
+      entries = append(entries, e)
+   }
+   slices.SortFunc(entries, func(a, b Entry) int { return a.Deleted.Compare(b.Deleted) })
+   return
+}
+
+
+// Restore moves the trashed note with the given (full or partial) ID back into the notes directory, and re-adds its
+// ID to the tags which referenced it when it was trashed.
+func Restore(id string) (*Entry, error) {
+   e, err := find(id)
+   if err != nil {
+      return nil, err
+   }
+   dest := filepath.Join(paths.Notes(), e.File)
+   if _, err := os.Stat(dest); err == nil {
+      return nil, fmt.Errorf("cannot restore %s, %s already exists", e.ID, dest)
+   }
+   tx := txn.New()
+   if err := move(tx, filepath.Join(paths.Trash(), e.File), dest); err != nil {
+      return nil, err
+   }
+   if err := tags.RetagTx(tx, e.ID, nil, sets.New(e.Tags...)); err != nil {
+      return nil, err
+   }
+   tx.Remove(e.recordPath())
+   return e, tx.Commit()
+}
+
+
+// move queues moving a note file as part of a Txn; written anew at dest, untouched, then removed from src.
+func move(tx *txn.Txn, src, dest string) error {
+   info, err := os.Stat(src)
+   if err != nil {
+      return err
+   }
+   data, err := os.ReadFile(src)
+   if err != nil {
+      return err
+   }
+   tx.Write(dest, data, info.Mode().Perm())
+   tx.Remove(src)
+   return nil
+}
+
+
+// Empty permanently deletes trashed notes which were trashed longer ago than olderThan, returning their records.
+func Empty(olderThan time.Duration) (removed []Entry, err error) {
+   entries, err := List()
+   if err != nil {
+      return
+   }
+   cutoff := time.Now().Add(-olderThan)
+   for _, e := range entries {
+      if e.Deleted.After(cutoff) {
+         continue
+      }
+      if err = os.Remove(filepath.Join(paths.Trash(), e.File)); err != nil && !os.IsNotExist(err) {
+         return
+      }
+      if err = os.Remove(e.recordPath()); err != nil {
+         return
+      }
+      removed = append(removed, e)
+   }
+   return removed, nil
+}
+
+
+// find looks up a trash entry by full or partial ID, in the same manner as note.FindByID.
+func find(id string) (*Entry, error) {
+   entries, err := List()
+   if err != nil {
+      return nil, err
+   }
+   var match *Entry
+   var candidates []string
+   for i, e := range entries {
+      if e.ID == id {
+         return &entries[i], nil
+      }
+      if id != "" && strings.HasPrefix(e.ID, id) {
+         match = &entries[i]
+         candidates = append(candidates, e.ID)
+      }
+   }
+   switch len(candidates) {
+      case 0:
+         return nil, fmt.Errorf("%w in trash: %s", note.ErrNotFound, id)
+      case 1:
+         return match, nil
+      default:
+         return nil, &note.AmbiguousIDError{Prefix: id, Candidates: candidates}
+   }
+}
+
+
+// recordPath returns the path of the record file for the entry.
+func (e *Entry) recordPath() string {
+   return filepath.Join(paths.Trash(), e.ID + recordExt)
+}
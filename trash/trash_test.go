@@ -0,0 +1,62 @@
+package trash
+
+import (
+   "path/filepath"
+   "testing"
+   "time"
+
+   "github.com/omnikron13/zelkata/internal/testenv"
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/paths"
+   "github.com/omnikron13/zelkata/tags"
+
+   "github.com/stretchr/testify/assert"
+   "k8s.io/apimachinery/pkg/util/sets"
+)
+
+
+func Test_TrashRestoreEmpty(t *testing.T) {
+   testenv.Setup(t)
+   n := note.New("A note destined for the trash.\n")
+   n.Tags = sets.New("Foo", "Bar")
+   if err := n.Save(); err != nil {
+      t.Fatalf("Failed to save note: %s", err)
+   }
+   if err := tags.Retag(n.ID, nil, n.Tags); err != nil {
+      t.Fatalf("Failed to tag note: %s", err)
+   }
+   notePath := filepath.Join(paths.Notes(), n.GenFileName())
+
+   e, err := Trash(n.ID[:8])
+   assert.Nil(t, err)
+   assert.Equal(t, n.ID, e.ID)
+   assert.Equal(t, []string{"Bar", "Foo"}, e.Tags)
+   assert.NoFileExists(t, notePath)
+   assert.FileExists(t, filepath.Join(paths.Trash(), n.GenFileName()))
+   tm, _ := tags.LoadAll()
+   assert.False(t, tm.Get("Foo").Notes.Has(n.ID))
+
+   entries, err := List()
+   assert.Nil(t, err)
+   assert.Equal(t, []string{n.ID}, []string{entries[0].ID})
+
+   e, err = Restore(n.ID)
+   assert.Nil(t, err)
+   assert.FileExists(t, notePath)
+   tm, _ = tags.LoadAll()
+   assert.True(t, tm.Get("Foo").Notes.Has(n.ID))
+   assert.True(t, tm.Get("Bar").Notes.Has(n.ID))
+
+   if _, err := Trash(n.ID); err != nil {
+      t.Fatalf("Failed to re-trash note: %s", err)
+   }
+   removed, err := Empty(time.Hour)
+   assert.Nil(t, err)
+   assert.Empty(t, removed)
+   removed, err = Empty(0)
+   assert.Nil(t, err)
+   assert.Len(t, removed, 1)
+   entries, _ = List()
+   assert.Empty(t, entries)
+   assert.NoFileExists(t, filepath.Join(paths.Trash(), n.GenFileName()))
+}
@@ -4,15 +4,26 @@ import (
    "os"
    "context"
 
+   "github.com/omnikron13/zelkata/tags"
+
    "github.com/urfave/cli/v3"
    bt "github.com/charmbracelet/bubbletea"
 )
 
 func MainTui(ctx context.Context, cmd *cli.Command) error {
-   tm := TagsTableModel{}
-   tm.Init()
-   p := bt.NewProgram(&tm, bt.WithAltScreen())
-   if m, err := p.Run(); err != nil { bt.ErrProgramKilled.Error() } else {
+   var model bt.Model
+   if cmd.Bool("untagged") || cmd.Bool("recent") {
+      nm := &NotesTableModel{Recent: cmd.Bool("recent")}
+      if cmd.Bool("untagged") { nm.Tag = tags.Untagged }
+      nm.Init()
+      model = nm
+   } else {
+      tm := &TagsTableModel{}
+      tm.Init()
+      model = tm
+   }
+   p := bt.NewProgram(model, bt.WithAltScreen())
+   if m, err := p.Run(); err != nil { return err } else {
       _ = m
       os.Exit(0)
    }
@@ -0,0 +1,150 @@
+package tui
+
+import (
+   "fmt"
+   "slices"
+   "strings"
+   "time"
+
+   "github.com/omnikron13/zelkata/lock"
+   "github.com/omnikron13/zelkata/note"
+   "github.com/omnikron13/zelkata/tags"
+
+   bt "github.com/charmbracelet/bubbletea"
+
+   "github.com/76creates/stickers"
+)
+
+
+// NotesTableModel is a table of notes; either those which have a given (possibly virtual) tag, such as tags.Untagged for
+// triaging notes which were saved without any tags, or the most recently edited ones.
+type NotesTableModel struct {
+   Tag string;
+   Recent bool;
+   Notes []note.Note;
+   headers []string;
+   table *stickers.TableSingleType[string];
+   selectedRow uint;
+   selectedCol uint;
+   err error;
+}
+
+
+func (m *NotesTableModel) Init() bt.Cmd {
+   m.selectedRow = 0
+   m.selectedCol = 0
+   m.Notes = nil
+   // Reading without the lock is still safe enough if another process is hogging it, as files are written atomically
+   if l, err := lock.Acquire(lock.Shared); err == nil {
+      defer l.Release()
+   }
+   m.err = nil
+   tm, err := tags.LoadAll()
+   if err != nil {
+      m.err = err
+      return nil
+   }
+   notes, err := note.ReadAll()
+   if err != nil {
+      m.err = err
+      return nil
+   }
+   tm.ApplyVirtual(notes)
+   if m.Tag == "" {
+      m.Notes = notes
+   } else if t := tm.Get(m.Tag); t != nil {
+      m.Notes = slices.DeleteFunc(notes, func(n note.Note) bool { return !t.Notes.Has(n.ID) && !n.Tags.Has(t.Name) })
+   }
+   if m.Recent {
+      slices.SortStableFunc(m.Notes, func(a, b note.Note) int { return b.LastModified().Compare(a.LastModified()) })
+   } else {
+      slices.SortStableFunc(m.Notes, func(a, b note.Note) int { return a.Created.Compare(b.Created) })
+   }
+   m.headers = []string{
+      "󰎞 Title",
+      "Created",
+      "Modified",
+      "ID",
+      "󱤇 Tags",
+   }
+   m.table = stickers.NewTableSingleType[string](240, max(1, min(60, len(m.Notes))), m.headers)
+
+   rows := make([][]string, 0, len(m.Notes))
+   for _, n := range m.Notes {
+      rows = append(rows, []string{
+         n.DisplayTitle(),
+         n.Created.Format(time.DateTime),
+         n.LastModified().Format(time.DateTime),
+         n.ID,
+         strings.Join(tm.NoteTags(&n), ", "),
+      })
+   }
+   m.table.AddRows(rows)
+   return nil
+}
+
+
+func (m *NotesTableModel) Update(msg bt.Msg) (bt.Model, bt.Cmd) {
+   if m.table == nil { m.Init() }
+   switch msg := msg.(type) {
+      case bt.KeyMsg:
+         switch msg.String() {
+            case fmt.Sprintf("%s", bt.KeyUp), "i", "I":
+               if m.selectedRow > 0 {
+                  m.selectedRow--
+                  m.table.CursorUp()
+               }
+
+            case fmt.Sprintf("%s", bt.KeyDown), "k", "K":
+               if int(m.selectedRow) < len(m.Notes) - 1 {
+                  m.selectedRow++
+                  m.table.CursorDown()
+               }
+
+            case fmt.Sprintf("%s", bt.KeyLeft), "j", "J":
+               if m.selectedCol > 0 {
+                  m.selectedCol--
+                  m.table.CursorLeft()
+               }
+
+            case fmt.Sprintf("%s", bt.KeyRight), "l", "L":
+               if int(m.selectedCol) < len(m.headers) - 1 {
+                  m.selectedCol++
+                  m.table.CursorRight()
+               }
+
+            case "u", "U":
+               nm := &NotesTableModel{Tag: tags.Untagged}
+               nm.Init()
+               return nm, nil
+
+            case "r", "R":
+               nm := &NotesTableModel{Recent: true}
+               nm.Init()
+               return nm, nil
+
+            case "t", "T":
+               tm := &TagsTableModel{}
+               tm.Init()
+               return tm, nil
+
+            case "q", "Q":
+               return m, bt.Quit
+         }
+   }
+   return m, nil
+}
+
+
+func (m *NotesTableModel) View() string {
+   if m.err != nil {
+      return fmt.Sprintf("Error loading notes: %s\n", m.err)
+   }
+   if len(m.Notes) == 0 {
+      if m.Tag == "" {
+         return "No notes.\n"
+      }
+      return fmt.Sprintf("No notes tagged %s.\n", m.Tag)
+   }
+   return m.table.Render()
+}
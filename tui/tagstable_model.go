@@ -3,6 +3,8 @@ package tui
 import (
    "fmt"
 
+   "github.com/omnikron13/zelkata/lock"
+   "github.com/omnikron13/zelkata/note"
    "github.com/omnikron13/zelkata/tags"
 
    bt "github.com/charmbracelet/bubbletea"
@@ -33,7 +35,14 @@ type TagsTableModel struct {
 func (m *TagsTableModel) Init() bt.Cmd {
    m.selectedRow = 0
    m.selectedCol = 0
+   // Take a shared lock if possible, so as not to catch the tags half way through a rename etc.
+   if l, err := lock.Acquire(lock.Shared); err == nil {
+      defer l.Release()
+   }
    m.Tags, _ = tags.LoadAll();
+   if notes, err := note.ReadAll(); err == nil {
+      m.Tags.ApplyVirtual(notes)
+   }
    m.HashMap = make(map[string]tags.Tag)
    for _, t := range m.HashMap {
       if fn, err := t.GenFileName(); err != nil {
@@ -111,6 +120,16 @@ func (m *TagsTableModel) Update(msg bt.Msg) (bt.Model, bt.Cmd) {
                   m.table.CursorRight()
                }
 
+            case "u", "U":
+               nm := &NotesTableModel{Tag: tags.Untagged}
+               nm.Init()
+               return nm, nil
+
+            case "r", "R":
+               nm := &NotesTableModel{Recent: true}
+               nm.Init()
+               return nm, nil
+
             case "q", "Q":
                return m, bt.Quit
             default:
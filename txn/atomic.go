@@ -0,0 +1,74 @@
+package txn
+
+import (
+   "errors"
+   "io/fs"
+   "os"
+   "path/filepath"
+)
+
+
+// WriteFile writes data to the file at path atomically; the data is written to a temporary file in the same directory,
+// synced to disk, and then renamed over the top of path. The file is therefore either entirely as it was or entirely
+// replaced, even if the write is interrupted by a crash or the disk filling up.
+func WriteFile(path string, data []byte, perm fs.FileMode) error {
+   dir, name := filepath.Split(path)
+   if dir == "" {
+      dir = "."
+   }
+   f, err := os.CreateTemp(dir, "." + name + ".tmp*")
+   if err != nil {
+      return err
+   }
+   tmp := f.Name()
+   // Until the rename the temporary file is just litter if anything goes wrong
+   fail := func(err error) error {
+      f.Close()
+      os.Remove(tmp)
+      return err
+   }
+   if _, err := f.Write(data); err != nil {
+      return fail(err)
+   }
+   if err := f.Chmod(perm); err != nil {
+      return fail(err)
+   }
+   if err := f.Sync(); err != nil {
+      return fail(err)
+   }
+   if err := f.Close(); err != nil {
+      os.Remove(tmp)
+      return err
+   }
+   if err := os.Rename(tmp, path); err != nil {
+      os.Remove(tmp)
+      return err
+   }
+   return syncDir(dir)
+}
+
+
+// RemoveFile removes the file at path, and syncs its directory so that the removal is durable. Removing a file which
+// doesn't exist isn't an error.
+func RemoveFile(path string) error {
+   if err := os.Remove(path); errors.Is(err, fs.ErrNotExist) {
+      return nil
+   } else if err != nil {
+      return err
+   }
+   return syncDir(filepath.Dir(path))
+}
+
+
+// syncDir syncs a directory, which is what makes a rename or removal of a file in it durable.
+func syncDir(dir string) error {
+   d, err := os.Open(dir)
+   if err != nil {
+      return err
+   }
+   if err := d.Sync(); err != nil {
+      d.Close()
+      return err
+   }
+   return d.Close()
+}
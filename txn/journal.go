@@ -0,0 +1,116 @@
+package txn
+
+import (
+   "encoding/gob"
+   "errors"
+   "fmt"
+   "os"
+   "path/filepath"
+   "strings"
+
+   "github.com/omnikron13/zelkata/paths"
+)
+
+
+// journalExt is the extension of journal files, which also distinguishes them from WriteFile's temporary files.
+const journalExt = ".journal"
+
+
+// journal records a Txn which is in the middle of being applied; the changes being made, and the files as they were
+// beforehand.
+type journal struct {
+   Ops []op
+   Originals []original
+}
+
+
+// writeJournal writes a journal of the changes about to be made, returning its path.
+func writeJournal(ops []op, originals []original) (string, error) {
+   f, err := os.CreateTemp(paths.Journal(), "txn-*" + journalExt)
+   if err != nil {
+      return "", err
+   }
+   path := f.Name()
+   if err := gob.NewEncoder(f).Encode(journal{Ops: ops, Originals: originals}); err == nil {
+      err = f.Sync()
+   }
+   if err := errors.Join(err, f.Close()); err != nil {
+      os.Remove(path)
+      return "", err
+   }
+   return path, syncDir(paths.Journal())
+}
+
+
+// removeJournal removes a journal once the changes it records are either complete, or undone.
+func removeJournal(path string) error {
+   return RemoveFile(path)
+}
+
+
+// Recover finishes any Txn which was interrupted part way through being committed, as recorded in its journal. Each is
+// rolled forward, by simply making all of its changes again, or if that fails rolled back to how the files were before
+// it started. It should be called before anything else reads or writes the notes or tags.
+func Recover() (err error) {
+   journals, err := journals()
+   if err != nil {
+      return err
+   }
+   for _, path := range journals {
+      err = errors.Join(err, recoverJournal(path))
+   }
+   return
+}
+
+
+// Pending reports whether there are any journals, i.e. whether Recover has anything to do; so that the lock needed to
+// recover can be skipped in the usual case of there being nothing to recover. A journal may also be pending because
+// the process which wrote it is still committing it.
+func Pending() (bool, error) {
+   journals, err := journals()
+   return len(journals) > 0, err
+}
+
+
+// journals returns the paths of all of the journals in the journal directory.
+func journals() (journals []string, err error) {
+   entries, err := os.ReadDir(paths.Journal())
+   if err != nil {
+      return
+   }
+   for _, e := range entries {
+      if strings.HasSuffix(e.Name(), journalExt) {
+         journals = append(journals, filepath.Join(paths.Journal(), e.Name()))
+      }
+   }
+   return
+}
+
+
+// recoverJournal rolls forward (or failing that, back) the Txn recorded in a single journal.
+func recoverJournal(path string) error {
+   f, err := os.Open(path)
+   if err != nil {
+      return err
+   }
+   var j journal
+   err = gob.NewDecoder(f).Decode(&j)
+   f.Close()
+   if err != nil {
+      // A journal which can't be read was never completely written, so the Txn never started changing files either
+      return removeJournal(path)
+   }
+
+   var fwdErr error
+   for _, o := range j.Ops {
+      if fwdErr = o.apply(); fwdErr != nil {
+         break
+      }
+   }
+   if fwdErr != nil {
+      if err := rollback(j.Originals); err != nil {
+         return fmt.Errorf("unable to recover interrupted changes recorded in %s: %w", path, errors.Join(fwdErr, err))
+      }
+   }
+   return removeJournal(path)
+}
@@ -0,0 +1,181 @@
+// Package txn groups changes to several files together so that they either all happen or, should any of them fail,
+// none of them do. This matters for operations like renaming a tag, which has to touch every note with the tag as well
+// as the tag files themselves; stopping half way would leave notes and tags disagreeing with each other.
+//
+// Every individual file is written atomically (see WriteFile), and a Txn touching more than one file leaves a journal
+// while it is being applied, so that if the process dies part way through the change can be finished (or, failing
+// that, undone) by Recover the next time Zelkata runs.
+package txn
+
+import (
+   "errors"
+   "fmt"
+   "io/fs"
+   "os"
+)
+
+
+// Txn is a set of pending file changes, applied in the order they were added when committed.
+type Txn struct {
+   ops []op
+   hooks []func() error
+   values map[any]any
+}
+
+
+// op is a single pending change; either writing data to a file, or removing it. The fields are exported only so that
+// it can be written to a journal.
+type op struct {
+   Path   string
+   Data   []byte
+   Perm   fs.FileMode
+   Remove bool
+}
+
+
+// original is a snapshot of a file from before the Txn touched it, so that it can be put back.
+type original struct {
+   Path   string
+   Data   []byte
+   Perm   fs.FileMode
+   Exists bool
+}
+
+
+// New returns an empty Txn.
+func New() *Txn {
+   return &Txn{}
+}
+
+
+// Write queues writing data to the file at path, creating it if necessary.
+func (t *Txn) Write(path string, data []byte, perm fs.FileMode) {
+   t.ops = append(t.ops, op{Path: path, Data: data, Perm: perm})
+}
+
+
+// Remove queues removing the file at path. Removing a file which doesn't exist isn't an error.
+func (t *Txn) Remove(path string) {
+   t.ops = append(t.ops, op{Path: path, Remove: true})
+}
+
+
+// OnCommit registers a function to be called after the Txn has been successfully committed, e.g. to update anything
+// derived from the files only once they are definitely in place.
+func (t *Txn) OnCommit(f func() error) {
+   t.hooks = append(t.hooks, f)
+}
+
+
+// Value returns the value stored in the Txn under key by SetValue, or nil if there isn't one. Much like a
+// context.Context, this lets other packages keep state alongside a Txn; e.g. gathering up work to be done once by an
+// OnCommit function, rather than registering a separate function for every file.
+func (t *Txn) Value(key any) any {
+   return t.values[key]
+}
+
+
+// SetValue stores a value in the Txn under key, replacing any already stored under it.
+func (t *Txn) SetValue(key, value any) {
+   if t.values == nil {
+      t.values = map[any]any{}
+   }
+   t.values[key] = value
+}
+
+
+// Len returns the number of queued changes.
+func (t *Txn) Len() int {
+   return len(t.ops)
+}
+
+
+// Commit applies all the queued changes. If any of them fail, those already applied are rolled back to how the files
+// were beforehand, and the original error is returned (along with any errors from rolling back). Once every change is
+// in place, the OnCommit functions are called in the order they were registered.
+func (t *Txn) Commit() error {
+   // Snapshot everything first, so nothing is touched at all if a file can't even be read
+   originals := make([]original, 0, len(t.ops))
+   for _, o := range t.ops {
+      orig, err := snapshot(o.Path)
+      if err != nil {
+         return err
+      }
+      originals = append(originals, orig)
+   }
+
+   // A single change is atomic anyway, so only needs journaling if there is more than one
+   var j string
+   if len(t.ops) > 1 {
+      var err error
+      if j, err = writeJournal(t.ops, originals); err != nil {
+         return err
+      }
+   }
+
+   for i, o := range t.ops {
+      if err := o.apply(); err != nil {
+         err = fmt.Errorf("error applying change to %s: %w", o.Path, err)
+         rbErr := rollback(originals[:i+1])
+         // If rolling back failed too the journal is left behind, so another attempt can be made by Recover
+         if rbErr == nil && j != "" {
+            rbErr = removeJournal(j)
+         }
+         return errors.Join(err, rbErr)
+      }
+   }
+   if j != "" {
+      if err := removeJournal(j); err != nil {
+         return err
+      }
+   }
+   t.ops = nil
+
+   var err error
+   for _, h := range t.hooks {
+      err = errors.Join(err, h())
+   }
+   t.hooks = nil
+   return err
+}
+
+
+// apply makes the change to the file.
+func (o op) apply() error {
+   if o.Remove {
+      return RemoveFile(o.Path)
+   }
+   return WriteFile(o.Path, o.Data, o.Perm)
+}
+
+
+// snapshot records the current state of the file at path.
+func snapshot(path string) (original, error) {
+   info, err := os.Stat(path)
+   if errors.Is(err, fs.ErrNotExist) {
+      return original{Path: path}, nil
+   }
+   if err != nil {
+      return original{}, err
+   }
+   data, err := os.ReadFile(path)
+   if err != nil {
+      return original{}, err
+   }
+   return original{Path: path, Data: data, Perm: info.Mode().Perm(), Exists: true}, nil
+}
+
+
+// rollback restores files to their snapshotted states, latest first so that files touched more than once end up as
+// they were at the very start.
+func rollback(originals []original) (err error) {
+   for i := len(originals) - 1; i >= 0; i-- {
+      o := originals[i]
+      if o.Exists {
+         err = errors.Join(err, WriteFile(o.Path, o.Data, o.Perm))
+      } else {
+         err = errors.Join(err, RemoveFile(o.Path))
+      }
+   }
+   return
+}
@@ -0,0 +1,135 @@
+package txn
+
+import (
+   "os"
+   "path/filepath"
+   "testing"
+
+   "github.com/omnikron13/zelkata/internal/testenv"
+   "github.com/omnikron13/zelkata/paths"
+
+   "github.com/stretchr/testify/assert"
+)
+
+
+func Test_Commit(t *testing.T) {
+   testenv.Setup(t)
+   dir := t.TempDir()
+   a := filepath.Join(dir, "a")
+   b := filepath.Join(dir, "b")
+   c := filepath.Join(dir, "c")
+   if err := os.WriteFile(a, []byte("a"), 0600); err != nil {
+      t.Fatalf("Failed to create test file: %s", err)
+   }
+   if err := os.WriteFile(b, []byte("b"), 0600); err != nil {
+      t.Fatalf("Failed to create test file: %s", err)
+   }
+
+   t.Run("success", func(t *testing.T) {
+      tx := New()
+      tx.Write(a, []byte("A"), 0600)
+      tx.Write(c, []byte("C"), 0600)
+      tx.Remove(b)
+      assert.Nil(t, tx.Commit())
+      assert.Equal(t, 0, tx.Len())
+      data, _ := os.ReadFile(a)
+      assert.Equal(t, "A", string(data))
+      data, _ = os.ReadFile(c)
+      assert.Equal(t, "C", string(data))
+      assert.NoFileExists(t, b)
+   })
+
+   t.Run("rollback", func(t *testing.T) {
+      tx := New()
+      tx.Write(a, []byte("AA"), 0600)
+      tx.Write(b, []byte("B"), 0600)
+      tx.Remove(c)
+      // Writing into a directory that doesn't exist fails, which must undo all of the above
+      tx.Write(filepath.Join(dir, "missing", "d"), []byte("D"), 0600)
+      assert.NotNil(t, tx.Commit())
+      data, _ := os.ReadFile(a)
+      assert.Equal(t, "A", string(data))
+      assert.NoFileExists(t, b)
+      data, _ = os.ReadFile(c)
+      assert.Equal(t, "C", string(data))
+      entries, _ := os.ReadDir(paths.Journal())
+      assert.Empty(t, entries)
+   })
+}
+
+
+func Test_WriteFile(t *testing.T) {
+   testenv.Setup(t)
+   dir := t.TempDir()
+   path := filepath.Join(dir, "file")
+   assert.Nil(t, WriteFile(path, []byte("one"), 0600))
+   assert.Nil(t, WriteFile(path, []byte("two"), 0644))
+   data, _ := os.ReadFile(path)
+   assert.Equal(t, "two", string(data))
+   info, err := os.Stat(path)
+   if assert.Nil(t, err) {
+      assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+   }
+   // Nothing but the file itself should be left behind
+   entries, _ := os.ReadDir(dir)
+   assert.Len(t, entries, 1)
+
+   assert.NotNil(t, WriteFile(filepath.Join(dir, "missing", "file"), []byte("three"), 0600))
+}
+
+
+func Test_Recover(t *testing.T) {
+   testenv.Setup(t)
+   dir := t.TempDir()
+   a := filepath.Join(dir, "a")
+   b := filepath.Join(dir, "b")
+   if err := os.WriteFile(a, []byte("a"), 0600); err != nil {
+      t.Fatalf("Failed to create test file: %s", err)
+   }
+
+   t.Run("roll forward", func(t *testing.T) {
+      // As if the process died after writing the journal and the first change, but before the second
+      ops := []op{{Path: a, Data: []byte("A"), Perm: 0600}, {Path: b, Data: []byte("B"), Perm: 0600}}
+      if _, err := writeJournal(ops, []original{{Path: a, Data: []byte("a"), Perm: 0600, Exists: true}, {Path: b}}); err != nil {
+         t.Fatalf("Failed to write journal: %s", err)
+      }
+      assert.Nil(t, ops[0].apply())
+      pending, err := Pending()
+      assert.Nil(t, err)
+      assert.True(t, pending)
+      assert.Nil(t, Recover())
+      data, _ := os.ReadFile(a)
+      assert.Equal(t, "A", string(data))
+      data, _ = os.ReadFile(b)
+      assert.Equal(t, "B", string(data))
+      entries, _ := os.ReadDir(paths.Journal())
+      assert.Empty(t, entries)
+      pending, err = Pending()
+      assert.Nil(t, err)
+      assert.False(t, pending)
+   })
+
+   t.Run("roll back", func(t *testing.T) {
+      // The second change can never succeed, so the first has to be undone instead
+      ops := []op{{Path: a, Data: []byte("AA"), Perm: 0600}, {Path: filepath.Join(dir, "missing", "c"), Data: []byte("C"), Perm: 0600}}
+      originals := []original{{Path: a, Data: []byte("A"), Perm: 0600, Exists: true}, {Path: ops[1].Path}}
+      if _, err := writeJournal(ops, originals); err != nil {
+         t.Fatalf("Failed to write journal: %s", err)
+      }
+      assert.Nil(t, ops[0].apply())
+      assert.Nil(t, Recover())
+      data, _ := os.ReadFile(a)
+      assert.Equal(t, "A", string(data))
+      entries, _ := os.ReadDir(paths.Journal())
+      assert.Empty(t, entries)
+   })
+
+   t.Run("unreadable journal", func(t *testing.T) {
+      if err := os.WriteFile(filepath.Join(paths.Journal(), "txn-torn" + journalExt), []byte("junk"), 0600); err != nil {
+         t.Fatalf("Failed to write journal: %s", err)
+      }
+      assert.Nil(t, Recover())
+      entries, _ := os.ReadDir(paths.Journal())
+      assert.Empty(t, entries)
+   })
+}